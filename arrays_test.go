@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestArrays(t *testing.T) {
+	expectOutput(t, `
+[]int floors = []int{1, 3};
+floors = append(floors, 4, 2);
+echo(floors);
+echo(len(floors));
+echo(floors[2]);
+floors[0] = 10;
+[]int copy = floors;
+copy[1] = 99;
+echo(floors);
+echo(copy);
+[4]bool doors = [4]bool{true};
+doors[3] = true;
+echo(doors);
+[][]int grid = [][]int{[]int{1, 2}, []int{3}};
+grid[1] = append(grid[1], 7);
+grid[0][1] = 5;
+echo(grid);`, "[1 3 4 2]", 4, 4, "[10 3 4 2]", "[10 99 4 2]", "[true false false true]", "[[1 5] [3 7]]")
+}
+
+func TestArrayArguments(t *testing.T) {
+	expectOutput(t, `
+[]int floors = []int{1, 2, 3};
+func sum([]int xs) int {
+  int total = 0;
+  for int i = 0; i < len(xs); i = i + 1 {
+    total = total + xs[i];
+  }
+  xs[0] = 1000;
+  return total;
+}
+echo(sum(floors));
+echo(floors[0]);`, 6, 1)
+}
+
+func TestAppend(t *testing.T) {
+	expectOutput(t, `
+[]int a = []int{1, 2};
+[]int b = a;
+a = append(a, 3);
+[]int c = append(a, 4, 5);
+echo(a);
+echo(b);
+echo(c);
+for int i = 0; i < 100; i = i + 1 {
+  a = append(a, i);
+}
+echo(len(a));
+echo(a[102]);`, "[1 2 3]", "[1 2]", "[1 2 3 4 5]", 103, 99)
+}
+
+func TestArrayErrors(t *testing.T) {
+	expectError(t, `[]int a = []int{1}; echo(a[1]);`, "index 1 out of range for array of length 1")
+	expectError(t, `[]int a = []int{1}; echo(a[-1]);`, "index -1 out of range for array of length 1")
+	expectError(t, `[2]int a = [2]int{1, 2, 3};`, "too many elements in array literal of type [2]int")
+	expectError(t, `[]int a = []int{true};`, "invalid element in array literal: expected int, got bool")
+	expectError(t, `[2]int a = [2]int{}; a = append(a, 1);`, "can only append to dynamic arrays, got [2]int")
+	expectError(t, `[]int a = []int{}; a = append(a, "b");`, "cannot append string to []int")
+	expectError(t, `int i = 0; echo(i[0]);`, "cannot index into int, a non-array value")
+	expectError(t, `[]int a = []int{1}; echo(a[true]);`, "array index must be int, got bool")
+}
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestCompoundAssignment(t *testing.T) {
+	expectOutput(t, `
+int x = 5;
+x += 3;
+echo(x);
+x -= 1;
+echo(x);
+x *= 4;
+echo(x);
+x /= 3;
+echo(x);
+x %= 5;
+echo(x);
+float f = 1.5;
+f += 1.0;
+echo(f);
+string s = "a";
+s += "bc";
+echo(s);`, 8, 7, 28, 9, 4, 2.5, "abc")
+}
+
+func TestIncrementDecrement(t *testing.T) {
+	expectOutput(t, `
+int x = 4;
+x++;
+echo(x);
+x--;
+x--;
+echo(x);
+float f = 1.5;
+f++;
+echo(f);
+int sum = 0;
+for int i = 0; i < 5; i++ {
+  sum += i;
+}
+echo(sum);
+for int i = 10; i > 0; i -= 4 {
+  echo(i);
+}`, 5, 3, 2.5, 10, 10, 6, 2)
+}
+
+func TestCompoundAssignmentErrors(t *testing.T) {
+	expectError(t, `bool b = true; b++;`, "cannot increment or decrement bool, expected int or float")
+	expectError(t, `int x = 1; x += 1.5;`, "cannot mix int and float without a conversion")
+	expectError(t, `string s = "a"; s -= "b";`, "invalid arithmetic operator for type string")
+	expectError(t, `missing += 1;`, "could not resolve variable name: missing")
+}
+
+func TestInferredDeclaration(t *testing.T) {
+	expectOutput(t, `
+a := 3;
+s := "x";
+f := 1.5;
+arr := []int{1, 2, 3};
+m := map[string]int{"k": 1};
+g := (int x, bool y) int {
+  if y {
+    return x;
+  }
+  return -x;
+};
+h := g;
+echo(h(a, false));
+echo(s + "y");
+echo(f * 2.0);
+echo(len(arr));
+echo(m["k"]);
+for i := 0; i < 2; i++ {
+  echo(i);
+}`, -3, "xy", 3, 3, 1, 0, 1)
+}
+
+func TestInferredDeclarationErrors(t *testing.T) {
+	expectError(t, `func f() void { return; } x := f();`, "cannot declare x from an expression of type void")
+	expectError(t, `a := 1; a := 2;`, "Redeclaration of variable: a")
+	expectError(t, `a := 1; a = "b";`, "invalid type assignment: expected int, got string")
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestClosureOutlivesScope(t *testing.T) {
+	expectOutput(t, `
+func counter(int start) func () int {
+  int n = start;
+  return () int {
+    n = n + 1;
+    return n;
+  };
+}
+func () int a = counter(0);
+func () int b = counter(10);
+echo(a());
+echo(a());
+echo(b());
+echo(a());`, 1, 2, 11, 3)
+}
+
+func TestClosureSharesVariables(t *testing.T) {
+	expectOutput(t, `
+int total = 0;
+func add(int x) int {
+  total = total + x;
+  return total;
+}
+add(3);
+add(4);
+echo(total);
+int n = 1;
+func () int get = () int { return n; };
+n = 5;
+echo(get());`, 7, 5)
+}
+
+func TestNestedClosures(t *testing.T) {
+	expectOutput(t, `
+func adder(int x) func (int) int {
+  return (int y) int {
+    func () int inner = () int {
+      return x + y;
+    };
+    return inner();
+  };
+}
+func (int) int add1 = adder(1);
+echo(add1(2));
+echo(add1(5));`, 3, 6)
+}
+
+func TestClosuresInLoop(t *testing.T) {
+	expectOutput(t, `
+[]func () int fs = []func () int{};
+for int i = 0; i < 3; i = i + 1 {
+  int captured = i * 10;
+  fs = append(fs, () int { return captured; });
+}
+func () int first = fs[0];
+func () int last = fs[2];
+echo(first());
+echo(last());`, 0, 20)
+}
+
+func TestClosureErrors(t *testing.T) {
+	expectError(t, `func f() int { return missing; }`, "could not resolve variable name: missing")
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestConstants(t *testing.T) {
+	expectOutput(t, `
+const FLOORS = 4;
+const TOP = FLOORS - 1;
+const TIMEOUT = 2.5 * 2.0;
+const NAME = "elev" + "ator";
+const DEBUG = FLOORS > 3 & !(TOP == 0);
+const MIDDLE = FLOORS > 3 ? 2 : 1;
+echo(FLOORS);
+echo(TOP);
+echo(TIMEOUT);
+echo(NAME);
+echo(DEBUG);
+echo(MIDDLE);
+for i := 0; i < FLOORS; i++ {
+  const SQ = -FLOORS;
+  echo(i + SQ);
+}`, 4, 3, 5, "elevator", true, 2, -4, -3, -2, -1)
+}
+
+func TestConstantsInFunctions(t *testing.T) {
+	expectOutput(t, `
+const TOP = 3;
+func top() int {
+  return TOP * 10;
+}
+func later() func () int {
+  return () int { return TOP + 1; };
+}
+f := later();
+int x = TOP;
+x += TOP;
+echo(top());
+echo(f());
+echo(x);
+{
+  int TOP = 10;
+  TOP++;
+  echo(TOP);
+}`, 30, 4, 6, 11)
+}
+
+func TestConstantErrors(t *testing.T) {
+	expectError(t, `const A = 3; A = 4;`, "cannot assign to A, it is a constant")
+	expectError(t, `const A = 3; A += 1;`, "cannot assign to A, it is a constant")
+	expectError(t, `const A = 3; A++;`, "cannot assign to A, it is a constant")
+	expectError(t, `const A = 3; A(1);`, "attempting to call A, a non-function variable")
+	expectError(t, `int x = 1; const A = x + 1;`, "invalid constant A: expression is not constant")
+	expectError(t, `g := () int { return 1; }; const A = g();`, "invalid constant A: expression is not constant")
+	expectError(t, `const A = []int{1};`, "invalid constant A: constants must be int, float, bool or string, got []int")
+	expectError(t, `const F = (int a) int { const B = 2; return a + B; };`, "invalid constant F: constants must be int, float, bool or string")
+	expectError(t, `const A = 1; const A = 2;`, "redeclaration of variable: A")
+}
+
+func TestConstantDivisionByZero(t *testing.T) {
+	expectError(t, `const A = 5 / 0;`, "invalid constant A: invalid constant expression: runtime error: integer divide by zero")
+	expectError(t, `const A = 5 % (2 - 2);`, "invalid constant A: invalid constant expression")
+}
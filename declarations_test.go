@@ -0,0 +1,129 @@
+package main
+
+import "testing"
+
+func TestMutualRecursion(t *testing.T) {
+	expectOutput(t, `
+echo(is_even(10));
+echo(is_odd(7));
+func is_even(int n) bool {
+  if n == 0 {
+    return true;
+  }
+  return is_odd(n - 1);
+}
+func is_odd(int n) bool {
+  if n == 0 {
+    return false;
+  }
+  return is_even(n - 1);
+}`, true, true)
+}
+
+func TestForwardReferences(t *testing.T) {
+	expectOutput(t, `
+echo(describe(1));
+echo(describe("a"));
+func describe(int a) string {
+  return "int";
+}
+func describe(string s) string {
+  return "string " + s;
+}
+func outer() int {
+  func inner(int x) int {
+    return x * 2;
+  }
+  return inner(later());
+}
+func later() int {
+  return 21;
+}
+echo(outer());`, "int", "string a", 42)
+}
+
+func TestForwardReferencedTypes(t *testing.T) {
+	expectOutput(t, `
+echo(length(Point{x: 3, y: 4}));
+echo(origin().x);
+func length(Point p, int offset = ORIGIN_X) int {
+  return p.x + p.y + offset;
+}
+func origin() Point {
+  return Point{x: 1};
+}
+const ORIGIN_X = 0x10;
+type Point struct {
+  int x;
+  int y;
+}`, 23, 1)
+}
+
+func TestForwardReferencedConstants(t *testing.T) {
+	expectOutput(t, `
+echo(limit());
+echo(next());
+func limit() int {
+  return MAX_FLOOR;
+}
+func next(int floor = MAX_FLOOR) int {
+  return floor + 1;
+}
+const MAX_FLOOR = 100;
+echo(MAX_FLOOR);
+{
+  const MAX_FLOOR = 3;
+  echo(MAX_FLOOR);
+}`, 100, 101, 100, 3)
+}
+
+// Functions called before the declaration of a global they read see its zero value.
+func TestGlobalsReadAhead(t *testing.T) {
+	expectOutput(t, `
+echo(total());
+echo(total() + 1);
+echo(label() + "!");
+echo(len(items()));
+int count = 5;
+string name = "lift";
+[]int list = []int{1, 2};
+echo(total());
+func total() int {
+  return count;
+}
+func label() string {
+  return name;
+}
+func items() []int {
+  return list;
+}
+echo(len(items()));`, 0, 1, "!", 0, 5, 2)
+}
+
+func TestDeclarationErrors(t *testing.T) {
+	expectError(t, `
+func f(P p) int { return 1; }
+type P struct { int x; }
+type P struct { int y; }`, "redeclaration of type: P")
+	expectError(t, `
+func f(P p) int { return 1; }
+type P struct { P next; }`, "invalid recursive type: P")
+	expectError(t, `echo(missing());`, "could not resolve variable name: missing")
+	expectError(t, `
+func f() int {
+  return g;
+}
+int g = 3;`, "variable g is used before its declaration")
+	expectError(t, `
+echo(h);
+h := 1;`, "variable h is used before its declaration")
+	expectError(t, `
+func f() int {
+  return later();
+}
+later := () int { return 1; };`, "variable later is used before its declaration")
+	expectError(t, `
+func f() int { return 1; }
+const A = 1;
+const A = 2;`, "redeclaration of variable: A")
+}
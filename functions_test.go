@@ -0,0 +1,129 @@
+package main
+
+import "testing"
+
+func TestVoidFunctions(t *testing.T) {
+	expectOutput(t, `
+int hits = 0;
+func press(int floor) void {
+  if floor < 0 {
+    return;
+  }
+  hits += floor;
+}
+press(3);
+press(-1);
+press(4);
+echo(hits);
+func () void reset = () void {
+  hits = 0;
+};
+reset();
+echo(hits);`, 7, 0)
+}
+
+func TestReturnPaths(t *testing.T) {
+	expectOutput(t, `
+func sign(int a) int {
+  if a > 0 {
+    return 1;
+  } else if a < 0 {
+    return -1;
+  } else {
+    return 0;
+  }
+}
+echo(sign(-4));
+echo(sign(0));`, -1, 0)
+}
+
+func TestReturnErrors(t *testing.T) {
+	expectError(t, `func f() void { return 1; }`, "cannot return a value from a void function")
+	expectError(t, `func f() int { return "a"; }`, "invalid return type: expected int, got string")
+	expectError(t, `func f() int { return; }`, "missing return value, expected int")
+	expectError(t, `func f() void { return; } int x = f() + 1;`, "void function call used as a value")
+	expectError(t, `return;`, "return outside of function")
+	expectError(t, `func f() int { int a = 1; }`, "missing return in function f")
+	expectError(t, `func f(int a) int { if a > 1 { return 1; } }`, "missing return in function f")
+	expectError(t, `g := () int { echo(1); };`, "missing return in function literal")
+}
+
+func TestVariadicFunctions(t *testing.T) {
+	expectOutput(t, `
+func log(string prefix, int... values) void {
+  int total = 0;
+  for int i = 0; i < len(values); i++ {
+    total += values[i];
+  }
+  print(prefix, len(values), total);
+}
+log("none");
+log("one", 5);
+log("three", 1, 2, 3);
+sum := (int... xs) int {
+  int s = 0;
+  for int i = 0; i < len(xs); i++ {
+    s += xs[i];
+  }
+  return s;
+};
+echo(sum(4, 5, 6));`, "none 0 0", "one 1 5", "three 3 6", 15)
+}
+
+func TestPrint(t *testing.T) {
+	expectOutput(t, `
+print("mixed", 1, 2.5, true, "str", []int{1, 2}, map[string]int{"a": 1});
+print();`, "mixed 1 2.5 true str [1 2] map[a:1]", "")
+}
+
+func TestVariadicErrors(t *testing.T) {
+	expectError(t, `func f(int... a, int b) void { return; }`, "only the last argument can be variadic, got int... a")
+	expectError(t, `func f(int... a) void { return; } f(1, "b");`, "Argument list to function f invalid")
+}
+
+func TestDefaultAndNamedArguments(t *testing.T) {
+	expectOutput(t, `
+const DEFAULT_TIMEOUT = 5000;
+func wait_for_floor(int elevator, int floor, int timeout = DEFAULT_TIMEOUT, bool verbose = false) int {
+  if verbose {
+    print("waiting", elevator, floor, timeout);
+  }
+  return timeout;
+}
+echo(wait_for_floor(1, 2));
+echo(wait_for_floor(1, 2, 10));
+echo(wait_for_floor(1, 2, timeout: 10000));
+echo(wait_for_floor(1, floor: 3, verbose: true));
+echo(wait_for_floor(verbose: true, floor: 4, elevator: 0, timeout: 1));`,
+		5000, 10, 10000, "waiting 1 3 5000", 5000, "waiting 0 4 1", 1)
+}
+
+func TestDefaultArgumentsWithVariadic(t *testing.T) {
+	expectOutput(t, `
+func tag(string name, string sep = ":", string... parts) string {
+  string s = name;
+  for int i = 0; i < len(parts); i++ {
+    s += sep + parts[i];
+  }
+  return s;
+}
+echo(tag("a"));
+echo(tag("a", "-", "b", "c"));
+echo(tag("a", sep: "/"));`, "a", "a-b-c", "a")
+}
+
+func TestNamedArgumentErrors(t *testing.T) {
+	const f = "func f(int a, int b = 2) int { return a + b; }\n"
+	expectError(t, f+`f(a: 1, 2);`, "positional argument after named argument in call to f")
+	expectError(t, f+`f(1, c: 2);`, "unknown argument c in call to f")
+	expectError(t, f+`f(1, a: 2);`, "duplicate argument a in call to f")
+	expectError(t, f+`f(b: 1);`, "missing argument a in call to f")
+	expectError(t, `func f(int a = true) int { return a; }`, "invalid default value for a: expected int, got bool")
+	expectError(t, `
+func g() int {
+  int x = 1;
+  func f(int a = x) int { return a; }
+  return f();
+}`, "invalid constant a: expression is not constant")
+	expectError(t, `func f(int a = 1 / 0) int { return a; }`, "invalid constant a: invalid constant expression")
+}
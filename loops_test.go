@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestWhileLoop(t *testing.T) {
+	expectOutput(t, `
+int i = 0;
+while i < 5 {
+  i = i + 1;
+  if i == 2 {
+    continue;
+  }
+  if i == 4 {
+    break;
+  }
+  echo(i);
+}
+echo(i);`, 1, 3, 4)
+}
+
+func TestForLoop(t *testing.T) {
+	expectOutput(t, `
+for int j = 0; j < 10; j = j + 1 {
+  if j % 2 == 0 {
+    continue;
+  }
+  for int m = 0; m < 3; m = m + 1 {
+    if m == 2 { break; }
+    echo(j * 10 + m);
+  }
+  if j > 2 { break; }
+}`, 10, 11, 30, 31)
+}
+
+func TestReturnFromLoop(t *testing.T) {
+	expectOutput(t, `
+func find(int n) int {
+  int i = 0;
+  while true {
+    if i * i >= n {
+      return i;
+    }
+    i = i + 1;
+  }
+  return 0;
+}
+echo(find(50));`, 8)
+}
+
+func TestLoopErrors(t *testing.T) {
+	expectError(t, `break;`, "break outside of loop")
+	expectError(t, `continue;`, "continue outside of loop")
+	expectError(t, `while 1 { }`, "Expected boolean statement in while clause")
+	expectError(t, `
+while true {
+  func f() void {
+    break;
+  }
+}`, "cannot jump out of a function to an enclosing loop")
+}
@@ -7,6 +7,8 @@ import (
 	"dsl/storage"
 	"dsl/tokens"
 	"dsl/variables"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -14,14 +16,31 @@ import (
 )
 
 func main() {
+	max_call_depth := flag.Int("max-call-depth", runtime.DefaultMaxCallDepth, "maximum depth of nested function calls")
+	flag.Parse()
+
 	file_contents, err := os.ReadFile("testfiles/01.txt")
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
 
+	grammar := tokens.NewGrammar(tokens.NTGoal)
+	cfg := parser.CreateCFG()
+
 	start := time.Now()
-	_, scanner_stream := scanner.Lex("test_lexer", string(file_contents))
+	lr_parser := parser.CreateLRParser(grammar, cfg, parser.First(cfg, grammar))
+	fmt.Println("Created parse tables in ", time.Since(start))
+
+	if err := run(string(file_contents), lr_parser, cfg, grammar, *max_call_depth); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Scan, parse and run a program.
+func run(source string, lr_parser parser.LRParser, cfg parser.CFG, grammar tokens.Grammar, max_call_depth int) error {
+	start := time.Now()
+	_, scanner_stream, _ := scanner.Lex("test_lexer", source)
 
 	_exit := false
 	word_stream := make([]tokens.Token, 0)
@@ -32,8 +51,7 @@ func main() {
 			word_stream = append(word_stream, c)
 			_exit = true
 		case tokens.ItemError:
-			log.Fatal(c)
-			return
+			return errors.New(c.Lexeme)
 		default:
 			word_stream = append(word_stream, c)
 		}
@@ -41,13 +59,6 @@ func main() {
 
 	fmt.Println("Scanned in ", time.Since(start))
 
-	grammar := tokens.NewGrammar(tokens.NTGoal)
-	cfg := parser.CreateCFG()
-
-	start = time.Now()
-	parser := parser.CreateLRParser(grammar, cfg, parser.First(cfg, grammar))
-	fmt.Println("Created parse tables in ", time.Since(start))
-
 	words := make(chan tokens.Token)
 	go func() {
 		for i := range word_stream {
@@ -59,21 +70,26 @@ func main() {
 
 	storage := storage.NewStorage()
 	runtime := runtime.New()
+	runtime.MaxCallDepth = max_call_depth
 
 	generateGlobalFunctions(runtime, &storage)
+	if err := lr_parser.DeclareFunctions(word_stream, cfg, grammar, &storage); err != nil {
+		return err
+	}
 
 	start = time.Now()
-	entryPoint, err := parser.Parse(words, cfg, grammar, &storage, runtime)
+	entryPoint, frameSize, err := lr_parser.Parse(words, cfg, grammar, &storage, runtime)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	fmt.Println("Parsed in ", time.Since(start))
 
 	start = time.Now()
-	primary := runtime.NewInstance(entryPoint)
+	primary := runtime.NewInstance(entryPoint, frameSize)
 	primary.Run()
 	fmt.Println("Program finished in", time.Since(start))
+	return nil
 }
 
 func generateGlobalFunctions(rt *runtime.Runtime, storage *storage.Storage) {
@@ -82,7 +98,7 @@ func generateGlobalFunctions(rt *runtime.Runtime, storage *storage.Storage) {
 		ArgumentList: []variables.Argument{
 			{
 				Definition: variables.TypeDefinition{
-					BaseType: variables.INT,
+					BaseType: variables.ANY,
 				},
 				Identifier: "i",
 			},
@@ -101,4 +117,71 @@ func generateGlobalFunctions(rt *runtime.Runtime, storage *storage.Storage) {
 	})
 	storage.LoadInstruction(&runtime.InstrExitFunction{})
 	storage.DestroyFunctionScope(rt)
+
+	generatePrint(rt, storage)
+
+	generateConversion(rt, storage, "to_float", variables.INT, variables.FLOAT,
+		func(src variables.Symbol, dest variables.Symbol) runtime.Instruction {
+			return &runtime.InstrIntToFloat{Source: src, Dest: dest}
+		})
+	generateConversion(rt, storage, "to_int", variables.FLOAT, variables.INT,
+		func(src variables.Symbol, dest variables.Symbol) runtime.Instruction {
+			return &runtime.InstrFloatToInt{Source: src, Dest: dest}
+		})
+}
+
+// Declare print(any... values), printing any number of values of any type.
+func generatePrint(rt *runtime.Runtime, storage *storage.Storage) {
+	def := variables.TypeDefinition{
+		BaseType: variables.FUNC,
+		ArgumentList: []variables.Argument{
+			{
+				Definition: variables.TypeDefinition{
+					BaseType:    variables.ARRAY,
+					ElementType: &variables.TypeDefinition{BaseType: variables.ANY},
+				},
+				Identifier: "values",
+				Variadic:   true,
+			},
+		},
+		ReturnType: &variables.TypeDefinition{BaseType: variables.NONE},
+	}
+
+	storage.NewFunction("print", def)
+
+	values, err := storage.GetVarAddr("values")
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage.LoadInstruction(&runtime.InstrPrint{
+		Values: values,
+	})
+	storage.LoadInstruction(&runtime.InstrExitFunction{})
+	storage.DestroyFunctionScope(rt)
+}
+
+// Declare a built-in function converting its single argument from one type to another.
+func generateConversion(rt *runtime.Runtime, storage *storage.Storage, name string, from variables.Type, to variables.Type,
+	convert func(src variables.Symbol, dest variables.Symbol) runtime.Instruction) {
+	def := variables.TypeDefinition{
+		BaseType: variables.FUNC,
+		ArgumentList: []variables.Argument{
+			{
+				Definition: variables.TypeDefinition{BaseType: from},
+				Identifier: "i",
+			},
+		},
+		ReturnType: &variables.TypeDefinition{BaseType: to},
+	}
+
+	storage.NewFunction(name, def)
+
+	A, err := storage.GetVarAddr("i")
+	if err != nil {
+		log.Fatal(err)
+	}
+	ret := storage.NewLiteral(*def.ReturnType)
+	storage.LoadInstruction(convert(A, ret))
+	storage.LoadInstruction(&runtime.InstrExitFunction{RetVal: ret})
+	storage.DestroyFunctionScope(rt)
 }
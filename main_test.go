@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"dsl/color"
+	"dsl/parser"
+	"dsl/runtime"
+	"dsl/tokens"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Programs are run in a child process of the test binary, since errors end the process with log.Fatal.
+// The parse tables are created once by the parent, and passed to the children in a file.
+const (
+	sourceEnv       = "DSL_TEST_SOURCE"
+	tablesEnv       = "DSL_TEST_TABLES"
+	maxCallDepthEnv = "DSL_TEST_MAX_CALL_DEPTH"
+)
+
+var tablesPath string
+
+var logPrefix = regexp.MustCompile(`(?m)^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `)
+
+func TestMain(m *testing.M) {
+	grammar := tokens.NewGrammar(tokens.NTGoal)
+	cfg := parser.CreateCFG()
+
+	if source, ok := os.LookupEnv(sourceEnv); ok {
+		runChild(source, cfg, grammar)
+		os.Exit(0)
+	}
+
+	file, err := os.CreateTemp("", "dsl-tables-*.gob")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := gob.NewEncoder(file).Encode(parser.CreateLRParser(grammar, cfg, parser.First(cfg, grammar))); err != nil {
+		log.Fatal(err)
+	}
+	file.Close()
+	tablesPath = file.Name()
+
+	code := m.Run()
+	os.Remove(tablesPath)
+	os.Exit(code)
+}
+
+func runChild(source string, cfg parser.CFG, grammar tokens.Grammar) {
+	file, err := os.Open(os.Getenv(tablesEnv))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var lr_parser parser.LRParser
+	if err := gob.NewDecoder(file).Decode(&lr_parser); err != nil {
+		log.Fatal(err)
+	}
+	file.Close()
+
+	max_call_depth := runtime.DefaultMaxCallDepth
+	if depth, ok := os.LookupEnv(maxCallDepthEnv); ok {
+		max_call_depth, _ = strconv.Atoi(depth)
+	}
+	if err := run(source, lr_parser, cfg, grammar, max_call_depth); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// The result of running a program: the lines written by echo and print, and the error ending the program, if any.
+type result struct {
+	output []string
+	err    string
+}
+
+func runProgram(t *testing.T, source string, env ...string) result {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), append(env, sourceEnv+"="+source, tablesEnv+"="+tablesPath)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	var res result
+	err := cmd.Run()
+	var exit_err *exec.ExitError
+	if errors.As(err, &exit_err) {
+		// The error is the last message logged, which is prefixed with the date and time and may span several lines
+		messages := logPrefix.Split(stderr.String(), -1)
+		res.err = strings.TrimSpace(messages[len(messages)-1])
+		if exit_err.ExitCode() != 1 {
+			t.Fatalf("program crashed:\n%s", stderr.String())
+		}
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if _, echoed, ok := strings.Cut(line, color.Green); ok {
+			res.output = append(res.output, echoed)
+		}
+	}
+	return res
+}
+
+// Run source, which must succeed with the output lines expected.
+func expectOutput(t *testing.T, source string, expected ...any) {
+	t.Helper()
+	res := runProgram(t, source)
+	if res.err != "" {
+		t.Fatalf("unexpected error: %s", res.err)
+	}
+
+	lines := make([]string, len(expected))
+	for i := range expected {
+		lines[i] = fmt.Sprint(expected[i])
+	}
+	if !slices.Equal(res.output, lines) {
+		t.Fatalf("expected output %q, got %q", lines, res.output)
+	}
+}
+
+// Run source, which must fail with an error containing message.
+func expectError(t *testing.T, source string, message string, env ...string) {
+	t.Helper()
+	res := runProgram(t, source, env...)
+	if res.err == "" {
+		t.Fatalf("expected error %q, got output %q", message, res.output)
+	}
+	if !strings.Contains(res.err, message) {
+		t.Fatalf("expected error %q, got %q", message, res.err)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import "testing"
+
+func TestMaps(t *testing.T) {
+	expectOutput(t, `
+map[int]int last_seen = map[int]int{2: 1, 0: 3, 1: 0};
+last_seen[5] = 4;
+echo(last_seen);
+echo(last_seen[0]);
+echo(last_seen[9]);
+echo(has(last_seen, 9));
+echo(has(last_seen, 5));
+delete(last_seen, 5);
+echo(len(last_seen));
+map[int]int copy = last_seen;
+copy[0] = 100;
+echo(copy);
+echo(last_seen);
+map[string][]int orders = map[string][]int{"a": []int{1}};
+orders["a"] = append(orders["a"], 2);
+echo(orders);`,
+		"map[0:3 1:0 2:1 5:4]", 3, 0, false, true, 3, "map[0:100 1:0 2:1]", "map[0:3 1:0 2:1]", "map[a:[1 2]]")
+}
+
+func TestMapIteration(t *testing.T) {
+	expectOutput(t, `
+map[string]int counts = map[string]int{};
+[]string words = []string{"up", "down", "up", "stop", "up"};
+for int i = 0; i < len(words); i = i + 1 {
+  counts[words[i]] = counts[words[i]] + 1;
+}
+for w, c in counts {
+  echo(w);
+  echo(c);
+}
+map[bool]string m = map[bool]string{true: "t", false: "f"};
+for k in m {
+  if k { continue; }
+  echo(m[k]);
+}
+map[int]int deleted = map[int]int{1: 10, 2: 20};
+for k, v in deleted {
+  delete(deleted, k);
+  echo(v);
+}
+echo(deleted);`, "down", 1, "stop", 1, "up", 3, "f", 10, 20, "map[]")
+}
+
+// Writing through a missing key adds the key, rather than writing to a zero value that is not in the map.
+func TestWriteThroughMapEntry(t *testing.T) {
+	expectOutput(t, `
+type Floor struct {
+  int calls;
+  [2]bool buttons;
+}
+map[int]Floor floors = map[int]Floor{};
+echo(floors[1].calls);
+echo(len(floors));
+floors[1].calls = 3;
+floors[2].buttons[1] = true;
+floors[1].calls = floors[1].calls + 1;
+echo(floors[1].calls);
+echo(floors[2].buttons[1]);
+echo(len(floors));
+map[string]map[string]int nested = map[string]map[string]int{};
+nested["a"]["b"] = 1;
+echo(nested);
+map[string][2]int grid = map[string][2]int{};
+grid["x"][0] = 7;
+echo(grid);`, 0, 0, 4, true, 2, "map[a:map[b:1]]", "map[x:[7 0]]")
+}
+
+func TestMapErrors(t *testing.T) {
+	expectError(t, `map[float]int m = map[float]int{};`, "invalid map key type float, must be int, bool or string")
+	expectError(t, `map[int]int m = map[int]int{}; echo(m["a"]);`, "invalid key for map[int]int: expected int, got string")
+	expectError(t, `map[int]int m = map[int]int{true: 1};`, "invalid key in literal of type map[int]int: got bool")
+	expectError(t, `map[int]int m = map[int]int{1: "a"};`, "invalid value in literal of type map[int]int: got string")
+	expectError(t, `int i = 0; echo(has(i, 1));`, "expected a map, got int")
+	expectError(t, `int i = 0; for k in i { }`, "cannot iterate over int, a non-map value")
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestFloats(t *testing.T) {
+	expectOutput(t, `
+float t = 3.0;
+float open = 3.25;
+echo(open - t);
+echo(open - t < 0.5 & t - open < 0.5);
+echo(to_float(7) / 2.0);
+echo(to_int(7.9));
+echo(7 / 2);
+echo(1.5e3 * 2.0);
+echo(-2.5);`, 0.25, true, 3.5, 7, 3, 3000, -2.5)
+}
+
+func TestFloatErrors(t *testing.T) {
+	expectError(t, `float f = 1.0 + 2;`, "cannot mix float and int without a conversion")
+	expectError(t, `float f = 1;`, "cannot mix float and int without a conversion")
+	expectError(t, `int i = to_int(3);`, "Argument list to function to_int invalid")
+}
+
+func TestIntegerLiterals(t *testing.T) {
+	expectOutput(t, `
+echo(0xFF);
+echo(0XfF_fF);
+echo(0b1010_0101);
+echo(0B11);
+echo(0o755);
+echo(1_000_000);
+echo(010);
+echo(0x_1F);
+echo(1_000.5);
+echo(9223372036854775807);
+echo(-9223372036854775807 - 1);
+[0x3]int a = [0b11]int{1, 2, 3};
+echo(len(a));
+echo(7 - 0x1);`, 255, 65535, 165, 3, 493, 1000000, 10, 31, 1000.5, 9223372036854775807, -9223372036854775808, 3, 6)
+}
+
+func TestIntegerLiteralErrors(t *testing.T) {
+	expectError(t, `echo(0x);`, `bad number syntax: "0x"`)
+	expectError(t, `echo(0o8);`, `bad number syntax: "0o8"`)
+	expectError(t, `echo(0b12);`, `bad number syntax: "0b12"`)
+	expectError(t, `echo(1__0);`, `'_' must separate successive digits: "1__0"`)
+	expectError(t, `echo(10_);`, `'_' must separate successive digits: "10_"`)
+	expectError(t, `echo(9223372036854775808);`, "integer literal 9223372036854775808 is out of range")
+	// The literal is range checked before it is negated
+	expectError(t, `echo(-9223372036854775808);`, "integer literal 9223372036854775808 is out of range")
+	expectError(t, `echo(1e400);`, "float literal 1e400 is out of range")
+}
@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+func TestUnaryMinus(t *testing.T) {
+	expectOutput(t, `
+int a = 3;
+int b = 4;
+echo(-a);
+echo(-(a + b));
+echo(2 * -a);
+echo(-a * b - -b);
+echo(- -a);
+echo(-2.5 * 2.0);
+[]int arr = []int{1, 2};
+echo(-arr[1]);
+echo(a-1);`, -3, -7, -6, -8, 3, -5, -2, 2)
+}
+
+func TestLogicalNot(t *testing.T) {
+	expectOutput(t, `
+int a = 3;
+bool t = true;
+echo(!t);
+echo(!(a > 4));
+echo(!!t);
+echo(!!!(a > 4));
+echo(!!a == 3);
+if !(a == 3) {
+  echo(1);
+} else {
+  echo(0);
+}`, false, true, true, true, true, 0)
+}
+
+func TestUnaryErrors(t *testing.T) {
+	expectError(t, `echo(!"a");`, "invalid operand for !: expected bool, got string")
+	expectError(t, `echo(-true);`, "invalid operand for unary -: expected int or float, got bool")
+}
+
+func TestShortCircuit(t *testing.T) {
+	expectOutput(t, `
+int calls = 0;
+func touch(bool v) bool {
+  calls = calls + 1;
+  return v;
+}
+echo(touch(false) & touch(true));
+echo(calls);
+echo(touch(true) | touch(false));
+echo(calls);
+echo(touch(true) & touch(false));
+echo(calls);
+echo(touch(false) | touch(false) | touch(true) & touch(true));
+echo(calls);`, false, 1, true, 2, false, 4, true, 8)
+}
+
+func TestShortCircuitGuard(t *testing.T) {
+	expectOutput(t, `
+[]int arr = []int{1};
+int i = 5;
+if i < len(arr) & arr[i] == 1 {
+  echo(0);
+} else {
+  echo(2);
+}
+int x = 2;
+if x > 1 & x < 3 | x == 10 {
+  echo(1);
+}`, 2, 1)
+}
+
+func TestShortCircuitErrors(t *testing.T) {
+	expectError(t, `echo(1 & true);`, "invalid operand for & or |: expected bool, got int")
+}
+
+func TestConditionalExpression(t *testing.T) {
+	expectOutput(t, `
+const UP = 1;
+const DOWN = -1;
+func direction(int floor, int target) int {
+  return floor > target ? DOWN : target > floor ? UP : 0;
+}
+echo(direction(3, 1));
+echo(direction(1, 3));
+echo(direction(2, 2));
+echo((1 < 2 ? 10 : 20) * 2);
+m := map[string]int{"a": 1 > 0 ? 1 : 2, "b": 3};
+echo(m["a"]);
+func pick(bool first, int x, int y) int {
+  return first ? x : y;
+}
+echo(pick(false, x: 5, y: 6));
+f := true ? (int x) int { return x + 1; } : (int x) int { return x - 1; };
+echo(f(10));`, -1, 1, 0, 20, 1, 6, 11)
+}
+
+// Only the branch selected by the condition is evaluated.
+func TestConditionalExpressionBranches(t *testing.T) {
+	expectOutput(t, `
+int calls = 0;
+func side(int v) int {
+  calls++;
+  return v;
+}
+int a = true ? side(1) : side(2);
+int b = false ? side(3) : side(4);
+echo(a + b);
+echo(calls);`, 5, 2)
+}
+
+func TestConditionalExpressionErrors(t *testing.T) {
+	expectError(t, `echo(1 ? 2 : 3);`, "invalid condition in conditional expression: expected bool, got int")
+	expectError(t, `echo(true ? 2 : "a");`, "mismatched types in conditional expression: int and string")
+}
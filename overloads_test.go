@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestOverloads(t *testing.T) {
+	expectOutput(t, `
+func describe(int a) string {
+  return "int";
+}
+func describe(bool b) string {
+  return "bool";
+}
+func describe(string s, int n = 2) string {
+  return "string " + s;
+}
+func describe(float f, int... rest) string {
+  return "float";
+}
+echo(describe(1));
+echo(describe(true));
+echo(describe("x"));
+echo(describe(s: "y", n: 3));
+echo(describe(1.5, 1, 2));`, "int", "bool", "string x", "string y", "float")
+}
+
+func TestOverloadsInNestedScopes(t *testing.T) {
+	expectOutput(t, `
+func fact(int n) int {
+  if n <= 1 {
+    return 1;
+  }
+  return n * fact(n - 1);
+}
+func fact(float n) float {
+  return n;
+}
+echo(fact(5));
+echo(fact(2.5));
+func outer() int {
+  func fact(int a, int b) int {
+    return a + b;
+  }
+  return fact(2, 3);
+}
+echo(outer());`, 120, 2.5, 5)
+}
+
+func TestOverloadErrors(t *testing.T) {
+	expectError(t, `
+func f(int a, int b = 1) int { return 1; }
+func f(int a, bool b = true) int { return 2; }
+echo(f(1));`, "ambiguous call to f with arguments (int)")
+	expectError(t, `
+func f(int a) int { return 1; }
+func f(int b) int { return 2; }`, "redeclaration of function f with the same argument types")
+	expectError(t, `
+func f(int a) int { return 1; }
+func f(bool b) int { return 2; }
+echo(f("s"));`, "no overload of f with arguments (string)")
+	expectError(t, `
+func f(int a) int { return 1; }
+func f(bool b) int { return 2; }
+g := f;`, "cannot use overloaded function f as a value")
+}
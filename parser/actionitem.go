@@ -6,7 +6,9 @@ import (
 	"dsl/variables"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 type condition_tree_entry struct {
@@ -15,6 +17,11 @@ type condition_tree_entry struct {
 	jmp         *runtime.InstrJmpIf           // Instruction that starts the conditional block, of type InstrJmpIf. Can again be nil, for else statement.
 }
 
+type field_initializer struct {
+	name  string
+	value variables.Symbol
+}
+
 type List[T any] struct {
 	First  T
 	Second *List[T]
@@ -31,32 +38,294 @@ func (list List[T]) Iterate() (ret []T) {
 	return ret
 }
 
-// Convert string to integer. Should not fail!
-func intval(s string) int {
-	intval, err := strconv.Atoi(s)
+// Convert an integer literal to its value. The scanner checks the syntax, but the value may be out of range.
+// A literal is checked before any unary minus is applied to it, so the smallest int cannot be written as a
+// single literal, e.g. -9223372036854775808, and must be computed instead, e.g. -9223372036854775807 - 1.
+func intval(s string) (int, error) {
+	digits, base := strings.ReplaceAll(s, "_", ""), 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			digits, base = digits[2:], 16
+		case 'b', 'B':
+			digits, base = digits[2:], 2
+		case 'o', 'O':
+			digits, base = digits[2:], 8
+		}
+	}
+
+	intval, err := strconv.ParseInt(digits, base, strconv.IntSize)
+	if err != nil {
+		return 0, fmt.Errorf("integer literal %s is out of range", s)
+	}
+	return int(intval), nil
+}
+
+// Convert a float literal to its value. The scanner checks the syntax, but the value may be out of range.
+func floatval(s string) (float64, error) {
+	floatval, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
 	if err != nil {
-		panic(err)
+		return 0, fmt.Errorf("float literal %s is out of range", s)
 	}
-	return intval
+	return floatval, nil
 }
 
-func integerArithmetic(words []any, storage *storage.Storage, op runtime.Operator) variables.Symbol {
-	new_addr := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.INT})
-	storage.LoadInstruction(&runtime.InstrArithmetic{
-		A:        words[0].(variables.Symbol),
-		B:        words[2].(variables.Symbol),
-		Result:   new_addr,
-		Operator: op,
-	})
+// Ints and floats are never converted implicitly, a conversion must be written out.
+func validateNumericMix(a variables.Symbol, b variables.Symbol) error {
+	if (a.Type.BaseType == variables.INT && b.Type.BaseType == variables.FLOAT) ||
+		(a.Type.BaseType == variables.FLOAT && b.Type.BaseType == variables.INT) {
+		return fmt.Errorf("cannot mix %s and %s without a conversion, use to_float or to_int", a.Type, b.Type)
+	}
+	return nil
+}
+
+func validateArithmetic(a variables.Symbol, b variables.Symbol, op runtime.Operator) error {
+	if err := validateValue(a); err != nil {
+		return err
+	}
+	if err := validateValue(b); err != nil {
+		return err
+	}
+	if err := validateNumericMix(a, b); err != nil {
+		return err
+	}
+	if !a.Type.Equals(b.Type) {
+		return fmt.Errorf("invalid arithmetic on %s and %s", a.Type, b.Type)
+	}
+	switch a.Type.BaseType {
+	case variables.INT:
+		return nil
+	case variables.FLOAT:
+		if op != runtime.MOD {
+			return nil
+		}
+	case variables.STRING:
+		if op == runtime.ADD {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid arithmetic operator for type %s", a.Type)
+}
+
+func arithmetic(words []any, storage *storage.Storage, op runtime.Operator) variables.Symbol {
+	a := words[0].(variables.Symbol)
+	b := words[2].(variables.Symbol)
+
+	err := validateArithmetic(a, b, op)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	new_addr := storage.NewLiteral(a.Type)
+	loadArithmetic(a, b, new_addr, storage, op)
 	return new_addr
 }
+
+// Load the instruction computing "a op b" into result, for operands already validated.
+func loadArithmetic(a variables.Symbol, b variables.Symbol, result variables.Symbol, storage *storage.Storage, op runtime.Operator) {
+	if a.Type.BaseType == variables.STRING {
+		storage.LoadInstruction(&runtime.InstrConcat{
+			A:      a,
+			B:      b,
+			Result: result,
+		})
+	} else if a.Type.BaseType == variables.FLOAT {
+		storage.LoadInstruction(&runtime.InstrArithmeticFloat{
+			A:        a,
+			B:        b,
+			Result:   result,
+			Operator: op,
+		})
+	} else {
+		storage.LoadInstruction(&runtime.InstrArithmetic{
+			A:        a,
+			B:        b,
+			Result:   result,
+			Operator: op,
+		})
+	}
+}
+
+// The operator of a compound assignment, e.g. "+=" is ADD
+func assignmentOperator(lexeme string) runtime.Operator {
+	switch lexeme {
+	case "+=":
+		return runtime.ADD
+	case "-=":
+		return runtime.SUB
+	case "*=":
+		return runtime.MULT
+	case "/=":
+		return runtime.DIV
+	}
+	return runtime.MOD
+}
+
+// Compound assignment, e.g. a += b. The result is written directly into the variable.
+func doCompoundAssignment(name string, value variables.Symbol, storage *storage.Storage, op runtime.Operator) (variables.Symbol, error) {
+	dest, err := storage.GetAssignableAddr(name)
+	if err != nil {
+		return dest, err
+	}
+	if err := validateArithmetic(dest, value, op); err != nil {
+		return dest, err
+	}
+	loadArithmetic(dest, value, dest, storage, op)
+	return dest, nil
+}
+
+// a++ and a--, for int and float variables.
+func doIncrement(name string, storage *storage.Storage, op runtime.Operator) (variables.Symbol, error) {
+	dest, err := storage.GetAssignableAddr(name)
+	if err != nil {
+		return dest, err
+	}
+
+	one := storage.NewLiteral(dest.Type)
+	switch dest.Type.BaseType {
+	case variables.INT:
+		storage.LoadInstruction(&runtime.InstrLoadImmediate{Dest: one, Value: 1})
+	case variables.FLOAT:
+		storage.LoadInstruction(&runtime.InstrLoadImmediate{Dest: one, Value: 1.0})
+	default:
+		return dest, fmt.Errorf("cannot increment or decrement %s, expected int or float", dest.Type)
+	}
+	loadArithmetic(dest, one, dest, storage, op)
+	return dest, nil
+}
+
 func validateBooleanArithmetic(a variables.Symbol, b variables.Symbol, op runtime.BooleanOperator) error {
+	if err := validateValue(a); err != nil {
+		return err
+	}
+	if err := validateValue(b); err != nil {
+		return err
+	}
+	if err := validateNumericMix(a, b); err != nil {
+		return err
+	}
 	if a.Type.BaseType != b.Type.BaseType || !op.IsValidFor(a.Type.BaseType) {
 		return fmt.Errorf("invalid type comparison of %s and %s", a.Type, b.Type)
 	}
 	return nil
 }
 
+// Logical not, "! a"
+func doNot(a variables.Symbol, s *storage.Storage) (variables.Symbol, error) {
+	if a.Type.BaseType != variables.BOOL {
+		return variables.Symbol{}, fmt.Errorf("invalid operand for !: expected bool, got %s", a.Type)
+	}
+	result := s.NewLiteral(a.Type)
+	s.LoadInstruction(&runtime.InstrNot{A: a, Result: result})
+	return result, nil
+}
+
+// Unary minus, "- a"
+func doNegate(a variables.Symbol, s *storage.Storage) (variables.Symbol, error) {
+	result := s.NewLiteral(a.Type)
+	switch a.Type.BaseType {
+	case variables.INT:
+		s.LoadInstruction(&runtime.InstrNegate{A: a, Result: result})
+	case variables.FLOAT:
+		s.LoadInstruction(&runtime.InstrNegateFloat{A: a, Result: result})
+	default:
+		return variables.Symbol{}, fmt.Errorf("invalid operand for unary -: expected int or float, got %s", a.Type)
+	}
+	return result, nil
+}
+
+// The left operand of a short-circuiting & or |, and the label after its right operand.
+type short_circuit struct {
+	Left   variables.Symbol
+	Result variables.Symbol
+	End    string
+}
+
+// & and | evaluate their operands left to right, and only evaluate the right operand if the left operand
+// does not decide the result: a & b evaluates b only if a is true, a | b evaluates b only if a is false.
+// Side effects of the right operand, such as function calls, therefore only happen when it is evaluated.
+//
+// The result is set to the left operand, followed by a jump past the right operand if the result is decided.
+func beginShortCircuit(left variables.Symbol, s *storage.Storage, op runtime.BooleanOperator) (short_circuit, error) {
+	if left.Type.BaseType != variables.BOOL {
+		return short_circuit{}, fmt.Errorf("invalid operand for & or |: expected bool, got %s", left.Type)
+	}
+
+	sc := short_circuit{
+		Left:   left,
+		Result: s.NewLiteral(left.Type),
+		End:    s.NewAutoLabel(),
+	}
+	s.LoadInstruction(&runtime.InstrAssign{Dest: sc.Result, Source: left})
+
+	// InstrJmpIf jumps when its condition is false, which decides a & b. a | b is decided when a is true.
+	condition := sc.Result
+	if op == runtime.OR {
+		condition = s.NewLiteral(left.Type)
+		s.LoadInstruction(&runtime.InstrNot{A: sc.Result, Result: condition})
+	}
+	s.LoadInstruction(&runtime.InstrJmpIf{Condition: condition, Label: sc.End})
+	return sc, nil
+}
+
+// Once the right operand is evaluated, it decides the result.
+func endShortCircuit(sc short_circuit, right variables.Symbol, s *storage.Storage, op runtime.BooleanOperator) (variables.Symbol, error) {
+	if err := validateBooleanArithmetic(sc.Left, right, op); err != nil {
+		return variables.Symbol{}, err
+	}
+	s.LoadInstruction(&runtime.InstrAssign{Dest: sc.Result, Source: right})
+	s.LoadLabeledInstruction(&runtime.InstrNOP{}, sc.End)
+	return sc.Result, nil
+}
+
+// The labels of a conditional expression "cond ? a : b", and its result once the first branch is known.
+type conditional_expression struct {
+	Result variables.Symbol
+	Else   string
+	End    string
+}
+
+// Only the selected branch of a conditional expression is evaluated. The condition jumps to the second branch
+// when it is false, and the first branch jumps past the second branch once its value is stored in the result.
+func beginConditional(condition variables.Symbol, s *storage.Storage) (conditional_expression, error) {
+	if condition.Type.BaseType != variables.BOOL {
+		return conditional_expression{}, fmt.Errorf("invalid condition in conditional expression: expected bool, got %s", condition.Type)
+	}
+
+	ce := conditional_expression{
+		Else: s.NewAutoLabel(),
+		End:  s.NewAutoLabel(),
+	}
+	s.LoadInstruction(&runtime.InstrJmpIf{Condition: condition, Label: ce.Else})
+	return ce, nil
+}
+
+func doConditionalThen(ce conditional_expression, value variables.Symbol, s *storage.Storage) (conditional_expression, error) {
+	if err := validateValue(value); err != nil {
+		return ce, err
+	}
+
+	ce.Result = s.NewLiteral(value.Type)
+	s.LoadInstruction(&runtime.InstrAssign{Dest: ce.Result, Source: value})
+	s.LoadInstruction(&runtime.InstrJmp{Label: ce.End})
+	s.LoadLabeledInstruction(&runtime.InstrNOP{}, ce.Else)
+	return ce, nil
+}
+
+// Both branches must have the same type.
+func endConditional(ce conditional_expression, value variables.Symbol, s *storage.Storage) (variables.Symbol, error) {
+	if err := validateValue(value); err != nil {
+		return variables.Symbol{}, err
+	}
+	if !value.Type.Equals(ce.Result.Type) {
+		return variables.Symbol{}, fmt.Errorf("mismatched types in conditional expression: %s and %s", ce.Result.Type, value.Type)
+	}
+
+	s.LoadInstruction(&runtime.InstrAssign{Dest: ce.Result, Source: value})
+	s.LoadLabeledInstruction(&runtime.InstrNOP{}, ce.End)
+	return ce.Result, nil
+}
+
 func booleanArithmetic(words []any, s *storage.Storage, op runtime.BooleanOperator) variables.Symbol {
 	a := words[0].(variables.Symbol)
 	b := words[2].(variables.Symbol)
@@ -66,18 +335,37 @@ func booleanArithmetic(words []any, s *storage.Storage, op runtime.BooleanOperat
 		log.Fatalln(err.Error())
 	}
 
+	return loadComparison(a, b, s, op)
+}
+
+// Compare a and b, which must have the same type.
+func loadComparison(a variables.Symbol, b variables.Symbol, s *storage.Storage, op runtime.BooleanOperator) variables.Symbol {
 	newaddr := s.NewLiteral(variables.TypeDefinition{BaseType: variables.BOOL})
 	if a.Type.BaseType == variables.BOOL {
 		s.LoadInstruction(&runtime.InstrCompareBool{
-			A:        words[0].(variables.Symbol),
-			B:        words[2].(variables.Symbol),
+			A:        a,
+			B:        b,
 			Result:   newaddr,
 			Operator: op,
 		})
 	} else if a.Type.BaseType == variables.INT {
 		s.LoadInstruction(&runtime.InstrCompareInt{
-			A:        words[0].(variables.Symbol),
-			B:        words[2].(variables.Symbol),
+			A:        a,
+			B:        b,
+			Result:   newaddr,
+			Operator: op,
+		})
+	} else if a.Type.BaseType == variables.FLOAT {
+		s.LoadInstruction(&runtime.InstrCompareFloat{
+			A:        a,
+			B:        b,
+			Result:   newaddr,
+			Operator: op,
+		})
+	} else if a.Type.BaseType == variables.STRING {
+		s.LoadInstruction(&runtime.InstrCompareString{
+			A:        a,
+			B:        b,
 			Result:   newaddr,
 			Operator: op,
 		})
@@ -86,8 +374,22 @@ func booleanArithmetic(words []any, s *storage.Storage, op runtime.BooleanOperat
 }
 
 func doAssignment(src variables.Symbol, dest variables.Symbol, storage *storage.Storage) variables.Symbol {
+	if err := validateValue(src); err != nil {
+		log.Fatal(err)
+	}
+	if err := validateNumericMix(dest, src); err != nil {
+		log.Fatal(err)
+	}
 	if !src.Type.Equals(dest.Type) {
-		log.Fatalf("invalid type assignment: expected %s, got %s", src.Type.String(), dest.Type.String())
+		log.Fatalf("invalid type assignment: expected %s, got %s", dest.Type.String(), src.Type.String())
+	}
+
+	// a = append(a, ...) appends to a directly, rather than to a copy of a
+	if last := storage.LastInstruction(); last != nil {
+		if append_instr, ok := last.Instruction.(*runtime.InstrAppend); ok && append_instr.Result.SameVariable(src) && append_instr.Array.SameVariable(dest) {
+			append_instr.Result = dest
+			return dest
+		}
 	}
 
 	storage.LoadInstruction(&runtime.InstrAssign{
@@ -97,20 +399,193 @@ func doAssignment(src variables.Symbol, dest variables.Symbol, storage *storage.
 	return dest
 }
 
-func doFunctionCall(name string, arguments []variables.Symbol, storage *storage.Storage) (variables.Symbol, error) {
-	sym, err := storage.GetVarAddr(name)
+// Declare a variable with the type of src, e.g. a := 3
+func doInferredDeclaration(name string, src variables.Symbol, storage *storage.Storage) (variables.Symbol, error) {
+	switch src.Type.BaseType {
+	case variables.NONE:
+		return src, fmt.Errorf("cannot declare %s from an expression of type void", name)
+	case variables.INVALID, variables.ANY:
+		return src, fmt.Errorf("cannot infer the type of %s", name)
+	case variables.TUPLE:
+		return src, fmt.Errorf("cannot declare %s from a function call returning %s", name, src.Type)
+	}
+
+	dest, err := storage.NewVariable(src.Type, name)
 	if err != nil {
-		return sym, err
+		return src, err
+	}
+	return doAssignment(src, *dest, storage), nil
+}
+
+// An argument in an argument list. Name is set for named arguments in function calls, e.g. timeout: 10000
+type call_argument struct {
+	Name string
+	variables.Symbol
+}
+
+// The values of an argument list that only allows positional arguments, e.g. in an array literal.
+func positionalArguments(arguments []call_argument) ([]variables.Symbol, error) {
+	var symbols []variables.Symbol
+	for _, argument := range arguments {
+		if argument.Name != "" {
+			return nil, fmt.Errorf("named argument %s outside of a function call", argument.Name)
+		}
+		symbols = append(symbols, argument.Symbol)
+	}
+	return symbols, nil
+}
+
+// The arguments of a call matched to the arguments of a function. Fixed holds the argument for each
+// non-variadic function argument, or nil where the default value is used. Trailing holds variadic arguments.
+type argument_match struct {
+	Fixed    []*variables.Symbol
+	Trailing []variables.Symbol
+}
+
+// Match the arguments of a call to the arguments of the function. Positional arguments come first, then named arguments.
+// Arguments that are left out get their default value.
+func matchArguments(name string, list variables.ArgumentList, arguments []call_argument) (argument_match, error) {
+	fixed := len(list)
+	if list.IsVariadic() {
+		fixed -= 1
+	}
+
+	match := argument_match{Fixed: make([]*variables.Symbol, fixed)}
+	named := false
+	for i := range arguments {
+		if arguments[i].Name == "" {
+			if named {
+				return match, fmt.Errorf("positional argument after named argument in call to %s", name)
+			}
+			if i < fixed {
+				match.Fixed[i] = &arguments[i].Symbol
+			} else {
+				match.Trailing = append(match.Trailing, arguments[i].Symbol)
+			}
+			continue
+		}
+
+		named = true
+		index := slices.IndexFunc(list[:fixed], func(arg variables.Argument) bool {
+			return arg.Identifier == arguments[i].Name
+		})
+		if index < 0 {
+			return match, fmt.Errorf("unknown argument %s in call to %s", arguments[i].Name, name)
+		}
+		if match.Fixed[index] != nil {
+			return match, fmt.Errorf("duplicate argument %s in call to %s", arguments[i].Name, name)
+		}
+		match.Fixed[index] = &arguments[i].Symbol
+	}
+
+	for i := range match.Fixed {
+		if match.Fixed[i] == nil && list[i].Default == nil {
+			return match, fmt.Errorf("missing argument %s in call to %s", list[i].Identifier, name)
+		}
+	}
+	return match, nil
+}
+
+// The types of the matched arguments, where default values have the type of the function argument.
+func (match argument_match) types(list variables.ArgumentList) []variables.Symbol {
+	var symbols []variables.Symbol
+	for i := range match.Fixed {
+		if match.Fixed[i] != nil {
+			symbols = append(symbols, *match.Fixed[i])
+		} else {
+			symbols = append(symbols, variables.Symbol{Type: list[i].Definition})
+		}
+	}
+	return append(symbols, match.Trailing...)
+}
+
+// Load the default values of arguments that were left out, and return the arguments to pass.
+func loadArguments(list variables.ArgumentList, match argument_match, storage *storage.Storage) []variables.Symbol {
+	var symbols []variables.Symbol
+	for i := range match.Fixed {
+		if match.Fixed[i] != nil {
+			symbols = append(symbols, *match.Fixed[i])
+			continue
+		}
+		value := storage.NewLiteral(list[i].Definition)
+		storage.LoadInstruction(&runtime.InstrLoadImmediate{
+			Dest:  value,
+			Value: list[i].Default,
+		})
+		symbols = append(symbols, value)
+	}
+	return append(symbols, match.Trailing...)
+}
+
+// Select the function to call among the overloads of name, by exact match of the argument types.
+func selectOverload(name string, candidates []variables.Symbol, arguments []call_argument) (variables.Symbol, argument_match, error) {
+	if len(candidates) == 1 {
+		sym := candidates[0]
+		if sym.Type.BaseType != variables.FUNC {
+			return sym, argument_match{}, fmt.Errorf("attempting to call %s, a non-function variable", name)
+		}
+		match, err := matchArguments(name, sym.Type.ArgumentList, arguments)
+		return sym, match, err
+	}
+
+	var matches []variables.Symbol
+	var match_args []argument_match
+	for _, sym := range candidates {
+		match, err := matchArguments(name, sym.Type.ArgumentList, arguments)
+		if err == nil && sym.Type.ArgumentList.ValidateArgumentList(match.types(sym.Type.ArgumentList)) {
+			matches = append(matches, sym)
+			match_args = append(match_args, match)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], match_args[0], nil
+	}
+
+	var argument_types, signatures []string
+	for _, argument := range arguments {
+		argument_types = append(argument_types, argument.Type.String())
+	}
+	for _, sym := range candidates {
+		signatures = append(signatures, "\t"+sym.Type.String())
+	}
+	problem := "no overload of"
+	if len(matches) > 1 {
+		problem = "ambiguous call to"
+	}
+	return variables.Symbol{}, argument_match{}, fmt.Errorf("%s %s with arguments (%s), candidates are:\n%s",
+		problem, name, strings.Join(argument_types, ", "), strings.Join(signatures, "\n"))
+}
+
+func doFunctionCall(name string, call_arguments []call_argument, storage *storage.Storage) (variables.Symbol, error) {
+	for _, argument := range call_arguments {
+		if err := validateValue(argument.Symbol); err != nil {
+			return variables.Symbol{}, err
+		}
+	}
+
+	candidates, err := storage.GetOverloads(name)
+	if err != nil {
+		return variables.Symbol{}, err
 	}
-	if sym.Type.BaseType != variables.FUNC {
-		return sym, fmt.Errorf("attempting to call %s, a non-function variable", name)
+	sym, match, err := selectOverload(name, candidates, call_arguments)
+	if err != nil {
+		return sym, err
 	}
 
+	arguments := loadArguments(sym.Type.ArgumentList, match, storage)
 	if !sym.Type.ArgumentList.ValidateArgumentList(arguments) {
 		return sym, fmt.Errorf("Argument list to function %s invalid\n", name)
 	}
+	if sym.Type.ArgumentList.IsVariadic() {
+		arguments = packVariadic(sym.Type.ArgumentList, arguments, storage)
+	}
 
-	ret_val := storage.NewLiteral(*sym.Type.ReturnType)
+	var ret_val variables.Symbol
+	if sym.Type.ReturnType.BaseType == variables.TUPLE {
+		ret_val = storage.NewTuple(*sym.Type.ReturnType)
+	} else {
+		ret_val = storage.NewLiteral(*sym.Type.ReturnType)
+	}
 	storage.LoadInstruction(&runtime.InstrCallFunction{
 		PreludeLength: 1,
 		RetVal:        ret_val,
@@ -118,33 +593,452 @@ func doFunctionCall(name string, arguments []variables.Symbol, storage *storage.
 		SymbolicLabel: sym,
 	})
 
-	return ret_val, nil
+	return ret_val, nil
+}
+
+// Return value from the current function. If value is the result of a call made just before,
+// the call is in tail position and is replaced by a tail call, which returns on behalf of this function.
+func doReturn(value variables.Symbol, storage *storage.Storage) error {
+	// The values of a call returning multiple values can be returned directly, e.g. return status(2);
+	if value.Type.BaseType != variables.TUPLE {
+		if err := validateValue(value); err != nil {
+			return err
+		}
+	}
+	ret_type, err := storage.ReturnType()
+	if err != nil {
+		return err
+	}
+	if ret_type.BaseType == variables.NONE {
+		return fmt.Errorf("cannot return a value from a void function")
+	}
+	if !value.Type.Equals(ret_type) {
+		return fmt.Errorf("invalid return type: expected %s, got %s", ret_type, value.Type)
+	}
+
+	if last := storage.LastInstruction(); last != nil && storage.InFunction() {
+		call, ok := last.Instruction.(*runtime.InstrCallFunction)
+		if ok && !value.Captured && value.Scope == 0 && call.RetVal.Scope == 0 && call.RetVal.Offset == value.Offset {
+			last.Instruction = &runtime.InstrTailCall{
+				Arguments:     call.Arguments,
+				SymbolicLabel: call.SymbolicLabel,
+			}
+			return nil
+		}
+	}
+
+	storage.LoadInstruction(&runtime.InstrExitFunction{
+		RetVal: value,
+	})
+	return nil
+}
+
+// Return multiple values, "return a, b;". The values are collected in a tuple.
+func doMultipleReturn(values []variables.Symbol, storage *storage.Storage) error {
+	_type := variables.TypeDefinition{BaseType: variables.TUPLE}
+	for _, value := range values {
+		if err := validateValue(value); err != nil {
+			return err
+		}
+		_type.Elements = append(_type.Elements, value.Type)
+	}
+
+	tuple := storage.NewTuple(_type)
+	for i, dest := range tuple.Values() {
+		storage.LoadInstruction(&runtime.InstrAssign{Dest: dest, Source: values[i]})
+	}
+	return doReturn(tuple, storage)
+}
+
+// Assign the values returned by a function to several variables, e.g. floor, direction := status(2);
+// With declare set, the variables are declared with the types of the values.
+func doDestructuring(names []string, value variables.Symbol, declare bool, storage *storage.Storage) error {
+	if value.Type.BaseType != variables.TUPLE {
+		if err := validateValue(value); err != nil {
+			return err
+		}
+		return fmt.Errorf("assignment mismatch: %d variables but 1 value", len(names))
+	}
+	values := value.Values()
+	if len(values) != len(names) {
+		return fmt.Errorf("assignment mismatch: %d variables but %d values", len(names), len(values))
+	}
+
+	for i, name := range names {
+		if declare {
+			if _, err := doInferredDeclaration(name, values[i], storage); err != nil {
+				return err
+			}
+			continue
+		}
+		dest, err := storage.GetAssignableAddr(name)
+		if err != nil {
+			return err
+		}
+		doAssignment(values[i], dest, storage)
+	}
+	return nil
+}
+
+// Return from a void function, "return;"
+func doVoidReturn(storage *storage.Storage) error {
+	ret_type, err := storage.ReturnType()
+	if err != nil {
+		return err
+	}
+	if ret_type.BaseType != variables.NONE {
+		return fmt.Errorf("missing return value, expected %s", ret_type)
+	}
+	storage.LoadInstruction(&runtime.InstrExitFunction{})
+	return nil
+}
+
+// End the body of a function. A function returning a value must not reach the end of its body.
+func doFunctionClose(storage *storage.Storage, r *runtime.Runtime) error {
+	ret_type, err := storage.ReturnType()
+	if err != nil {
+		return err
+	}
+	if ret_type.BaseType != variables.NONE && !storage.Headers && storage.ReachesEnd() {
+		name := storage.FunctionName()
+		if name == "" {
+			return fmt.Errorf("missing return in function literal")
+		}
+		return fmt.Errorf("missing return in function %s", name)
+	}
+
+	storage.LoadInstruction(&runtime.InstrExitFunction{})
+	storage.DestroyFunctionScope(r)
+	return nil
+}
+
+// Only the last argument of a function can be variadic.
+func validateArgumentDeclarations(arg_list []variables.Argument) error {
+	for i := range arg_list {
+		if arg_list[i].Variadic && i != len(arg_list)-1 {
+			return fmt.Errorf("only the last argument can be variadic, got %s", arg_list[i])
+		}
+	}
+	return nil
+}
+
+// Function argument with a default value, which must be a constant of the argument's type.
+func doDefaultArgument(arg variables.Argument, value variables.Symbol, storage *storage.Storage) (variables.Argument, error) {
+	if !value.Type.Equals(arg.Definition) {
+		return arg, fmt.Errorf("invalid default value for %s: expected %s, got %s", arg.Identifier, arg.Definition, value.Type)
+	}
+	result, err := storage.EvaluateConstant(value)
+	if err != nil {
+		return arg, err
+	}
+	arg.Default = result
+	return arg, nil
+}
+
+// Switch statements dispatch on ints, bools and strings.
+func doSwitchHeader(subject variables.Symbol, storage *storage.Storage) error {
+	if err := validateValue(subject); err != nil {
+		return err
+	}
+	switch subject.Type.BaseType {
+	case variables.INT, variables.BOOL, variables.STRING:
+	default:
+		return fmt.Errorf("cannot switch on a value of type %s, expected int, bool or string", subject.Type)
+	}
+	storage.BeginSwitch(subject)
+	return nil
+}
+
+// Evaluate a case value, which must be a constant of the type of the switch subject.
+func doCaseValue(value variables.Symbol, storage *storage.Storage) (any, error) {
+	sw := storage.CurrentSwitch()
+	if !value.Type.Equals(sw.Subject.Type) {
+		return nil, fmt.Errorf("invalid case value: expected %s, got %s", sw.Subject.Type, value.Type)
+	}
+	result, err := storage.EvaluateConstant(value)
+	if err != nil {
+		return nil, err
+	}
+	if sw.Values[result] {
+		return nil, fmt.Errorf("duplicate case %v in switch", result)
+	}
+	sw.Values[result] = true
+	return result, nil
+}
+
+// Compare the switch subject to each case value, and begin the body of the case when one is equal.
+func doCaseHeader(values []any, storage *storage.Storage) {
+	sw := storage.CurrentSwitch()
+	body := storage.NewAutoLabel()
+	for _, value := range values {
+		constant := storage.NewLiteral(sw.Subject.Type)
+		storage.LoadInstruction(&runtime.InstrLoadImmediate{
+			Dest:  constant,
+			Value: value,
+		})
+		different := loadComparison(sw.Subject, constant, storage, runtime.NOTEQUALS)
+		storage.LoadInstruction(&runtime.InstrJmpIf{Condition: different, Label: body})
+	}
+	storage.LoadInstruction(&runtime.InstrJmp{Label: sw.NextLabel})
+	storage.BeginScope().Label = body
+}
+
+// The default case is skipped by the comparisons, and run when no case matches, see storage.EndSwitch.
+func doDefaultCase(storage *storage.Storage) error {
+	sw := storage.CurrentSwitch()
+	if sw.DefaultLabel != "" {
+		return fmt.Errorf("multiple defaults in switch")
+	}
+	storage.BeginCase()
+	storage.LoadInstruction(&runtime.InstrJmp{Label: sw.NextLabel})
+	sw.DefaultLabel = storage.NewAutoLabel()
+	storage.BeginScope().Label = sw.DefaultLabel
+	return nil
+}
+
+// Collect the trailing arguments of a call to a variadic function into an array, which is passed as the last argument.
+func packVariadic(list variables.ArgumentList, arguments []variables.Symbol, storage *storage.Storage) []variables.Symbol {
+	fixed := len(list) - 1
+	variadic := list[fixed].Definition
+
+	values := storage.NewLiteral(variadic)
+	storage.LoadInstruction(&runtime.InstrMakeArray{
+		Elements: arguments[fixed:],
+		Zero:     variadic.ElementType.ZeroValue(),
+		Result:   values,
+	})
+	return append(slices.Clone(arguments[:fixed]), values)
+}
+
+// Calls to void functions are expressions, but have no value to use.
+func validateValue(a variables.Symbol) error {
+	if a.Type.BaseType == variables.NONE {
+		return fmt.Errorf("void function call used as a value")
+	}
+	if a.Type.BaseType == variables.TUPLE {
+		return fmt.Errorf("function call returning %s used as a single value", a.Type)
+	}
+	return nil
+}
+
+func doArrayLiteral(_type variables.TypeDefinition, elements []variables.Symbol, storage *storage.Storage) (variables.Symbol, error) {
+	if _type.Length > 0 && len(elements) > _type.Length {
+		return variables.Symbol{}, fmt.Errorf("too many elements in array literal of type %s", _type)
+	}
+	for _, element := range elements {
+		if !element.Type.Equals(*_type.ElementType) {
+			return variables.Symbol{}, fmt.Errorf("invalid element in array literal: expected %s, got %s", _type.ElementType, element.Type)
+		}
+	}
+
+	result := storage.NewLiteral(_type)
+	storage.LoadInstruction(&runtime.InstrMakeArray{
+		Elements: elements,
+		Length:   _type.Length,
+		Zero:     _type.ElementType.ZeroValue(),
+		Result:   result,
+	})
+	return result, nil
+}
+
+// Validate indexing into an array or a map.
+func validateIndex(array variables.Symbol, index variables.Symbol) error {
+	if array.Type.BaseType == variables.MAP {
+		if !index.Type.Equals(*array.Type.KeyType) {
+			return fmt.Errorf("invalid key for %s: expected %s, got %s", array.Type, array.Type.KeyType, index.Type)
+		}
+		return nil
+	}
+	if array.Type.BaseType != variables.ARRAY {
+		return fmt.Errorf("cannot index into %s, a non-array value", array.Type)
+	}
+	if index.Type.BaseType != variables.INT {
+		return fmt.Errorf("array index must be int, got %s", index.Type)
+	}
+	return nil
+}
+
+func validateMapKey(m variables.Symbol, key variables.Symbol) error {
+	if m.Type.BaseType != variables.MAP {
+		return fmt.Errorf("expected a map, got %s", m.Type)
+	}
+	return validateIndex(m, key)
+}
+
+type map_entry struct {
+	key   variables.Symbol
+	value variables.Symbol
+}
+
+func doMapLiteral(_type variables.TypeDefinition, entries []map_entry, storage *storage.Storage) (variables.Symbol, error) {
+	instr := &runtime.InstrMakeMap{}
+	for _, entry := range entries {
+		if !entry.key.Type.Equals(*_type.KeyType) {
+			return variables.Symbol{}, fmt.Errorf("invalid key in literal of type %s: got %s", _type, entry.key.Type)
+		}
+		if !entry.value.Type.Equals(*_type.ElementType) {
+			return variables.Symbol{}, fmt.Errorf("invalid value in literal of type %s: got %s", _type, entry.value.Type)
+		}
+		instr.Keys = append(instr.Keys, entry.key)
+		instr.Values = append(instr.Values, entry.value)
+	}
+
+	instr.Result = storage.NewLiteral(_type)
+	storage.LoadInstruction(instr)
+	return instr.Result, nil
+}
+
+// Set up a loop over the entries of a map, in sorted key order. The key and value variables are declared
+// in the current scope and assigned before each iteration. value is empty if only keys are iterated over.
+func doForIn(key string, value string, collection variables.Symbol, storage *storage.Storage) error {
+	if collection.Type.BaseType != variables.MAP {
+		return fmt.Errorf("cannot iterate over %s, a non-map value", collection.Type)
+	}
+	int_type := variables.TypeDefinition{BaseType: variables.INT}
+
+	keys := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.ARRAY, ElementType: collection.Type.KeyType})
+	values := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.ARRAY, ElementType: collection.Type.ElementType})
+	index := storage.NewLiteral(int_type)
+	one := storage.NewLiteral(int_type)
+	length := storage.NewLiteral(int_type)
+	condition := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.BOOL})
+
+	storage.LoadInstruction(&runtime.InstrMapEntries{Map: collection, Keys: keys, Values: values})
+	storage.LoadInstruction(&runtime.InstrLen{Source: keys, Result: length})
+	storage.LoadInstruction(&runtime.InstrLoadImmediate{Dest: index, Value: 0})
+	storage.LoadInstruction(&runtime.InstrLoadImmediate{Dest: one, Value: 1})
+
+	start := storage.NewAutoLabel()
+	storage.LoadLabeledInstruction(&runtime.InstrNOP{}, start)
+	loop := storage.BeginLoop(start)
+	storage.LoadInstruction(&runtime.InstrCompareInt{A: index, B: length, Operator: runtime.LESS, Result: condition})
+	storage.LoadInstruction(&runtime.InstrJmpIf{Condition: condition, Label: loop.BreakLabel})
+
+	key_sym, err := storage.NewVariable(*collection.Type.KeyType, key)
+	if err != nil {
+		return err
+	}
+	storage.LoadInstruction(&runtime.InstrIndexGet{Array: keys, Index: index, Result: *key_sym})
+	if value != "" {
+		value_sym, err := storage.NewVariable(*collection.Type.ElementType, value)
+		if err != nil {
+			return err
+		}
+		storage.LoadInstruction(&runtime.InstrIndexGet{Array: values, Index: index, Result: *value_sym})
+	}
+
+	loop.Post = []runtime.InstructionLabelPair{
+		{Instruction: &runtime.InstrArithmetic{A: index, B: one, Operator: runtime.ADD, Result: index}},
+	}
+	return nil
+}
+
+func doAppend(array variables.Symbol, values []variables.Symbol, storage *storage.Storage) (variables.Symbol, error) {
+	if array.Type.BaseType != variables.ARRAY || array.Type.Length > 0 {
+		return variables.Symbol{}, fmt.Errorf("can only append to dynamic arrays, got %s", array.Type)
+	}
+	for _, value := range values {
+		if !value.Type.Equals(*array.Type.ElementType) {
+			return variables.Symbol{}, fmt.Errorf("cannot append %s to %s", value.Type, array.Type)
+		}
+	}
+
+	result := storage.NewLiteral(array.Type)
+	storage.LoadInstruction(&runtime.InstrAppend{
+		Array:  array,
+		Values: values,
+		Result: result,
+	})
+	return result, nil
+}
+
+func doStructLiteral(_type variables.TypeDefinition, initializers []field_initializer, storage *storage.Storage) (variables.Symbol, error) {
+	instr := &runtime.InstrMakeStruct{
+		Zero: _type.ZeroValue().(*variables.StructVar),
+	}
+
+	for _, init := range initializers {
+		index, field_type, err := _type.Field(init.name)
+		if err != nil {
+			return variables.Symbol{}, err
+		}
+		if slices.Contains(instr.Indices, index) {
+			return variables.Symbol{}, fmt.Errorf("duplicate field %s in literal of type %s", init.name, _type)
+		}
+		if !init.value.Type.Equals(field_type) {
+			return variables.Symbol{}, fmt.Errorf("invalid value for field %s: expected %s, got %s", init.name, field_type, init.value.Type)
+		}
+		instr.Indices = append(instr.Indices, index)
+		instr.Values = append(instr.Values, init.value)
+	}
+
+	instr.Result = storage.NewLiteral(_type)
+	storage.LoadInstruction(instr)
+	return instr.Result, nil
+}
+
+// Writing to an element or field of a map entry, e.g. "m[key].field = Expr;", writes to the value read from the map.
+// A missing key reads a zero value that is not in the map, so the reads leading to dest are made to add their key.
+func insertMapEntries(dest variables.Symbol, storage *storage.Storage) {
+	instructions := storage.RecentInstructions()
+	for i := len(instructions) - 1; i >= 0; i-- {
+		switch instr := instructions[i].Instruction.(type) {
+		case *runtime.InstrMapGet:
+			if instr.Result.SameVariable(dest) {
+				instr.Insert = true
+				dest = instr.Map
+			}
+		case *runtime.InstrIndexGet:
+			if instr.Result.SameVariable(dest) {
+				dest = instr.Array
+			}
+		case *runtime.InstrFieldGet:
+			if instr.Result.SameVariable(dest) {
+				dest = instr.Struct
+			}
+		}
+	}
+}
+
+func validateField(value variables.Symbol, name string) (int, variables.TypeDefinition, error) {
+	if value.Type.BaseType != variables.STRUCT {
+		return -1, variables.TypeDefinition{}, fmt.Errorf("cannot access field %s of %s, a non-struct value", name, value.Type)
+	}
+	return value.Type.Field(name)
 }
 
 func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Runtime) any {
-	fmt.Println(rule_id, words)
 	switch rule_id {
 	case 3:
-		return integerArithmetic(words, storage, runtime.ADD)
+		return arithmetic(words, storage, runtime.ADD)
 	case 4:
-		return integerArithmetic(words, storage, runtime.SUB)
+		return arithmetic(words, storage, runtime.SUB)
 	case 6:
-		return integerArithmetic(words, storage, runtime.MULT)
+		return arithmetic(words, storage, runtime.MULT)
 	case 7:
-		return integerArithmetic(words, storage, runtime.DIV)
+		return arithmetic(words, storage, runtime.DIV)
+	case 9: // Parenthesized expression "( Expr )"
+		return words[1]
 	case 10: //New integer literal
+		value, err := intval(words[0].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
 		addr := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.INT})
 		storage.LoadInstruction(&runtime.InstrLoadImmediate{
 			Dest:  addr,
-			Value: intval(words[0].(string)),
+			Value: value,
 		})
 		return addr
 	case 11:
-		sym, err := storage.GetVarAddr(words[0].(string))
+		overloads, err := storage.GetOverloads(words[0].(string))
 		if err != nil {
 			log.Fatal(err)
 		}
-		return sym
+		if len(overloads) > 1 {
+			log.Fatalf("cannot use overloaded function %s as a value", words[0].(string))
+		}
+		return overloads[0]
 	case 12: // New variable, eg. int a = 3
 		_type := words[0].(variables.TypeDefinition)
 		src := words[3].(variables.Symbol)
@@ -155,40 +1049,55 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 
 		return doAssignment(src, *addr, storage)
 	case 13: // Reassignment of integer, e.g. a = 3
-		addr, err := storage.GetVarAddr(words[0].(string))
+		addr, err := storage.GetAssignableAddr(words[0].(string))
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		return doAssignment(words[2].(variables.Symbol), addr, storage)
 	case 16: // Declare scope
-		storage.LoadInstruction(&runtime.InstrBeginScope{})
-		storage.NewScope()
+		storage.BeginScope()
 	case 17: // End scope
 		storage.LoadInstruction(&runtime.InstrEndScope{})
 		storage.DestroyScope()
 	case 19: // call function e.g. echo ( 0 )
-		arg_list := (words[2].(List[variables.Symbol])).Iterate()
+		arg_list := (words[2].(List[call_argument])).Iterate()
 		func_name := words[0].(string)
 		sym, err := doFunctionCall(func_name, arg_list, storage)
 		if err != nil {
 			log.Fatal(err)
 		}
 		return sym
-	case 20: //argument list construction, input is "symbol , List"
-		second := words[2].(List[variables.Symbol])
-		return List[variables.Symbol]{
-			First:  words[0].(variables.Symbol),
+	case 20: //argument list construction, input is "argument , List"
+		second := words[2].(List[call_argument])
+		return List[call_argument]{
+			First:  words[0].(call_argument),
 			Second: &second,
 		}
 	case 21: //Initial list item in an argument list
-		return List[variables.Symbol]{
-			First:  words[0].(variables.Symbol),
+		return List[call_argument]{
+			First:  words[0].(call_argument),
 			Second: nil}
+	case 22: //Positional argument
+		return call_argument{Symbol: words[0].(variables.Symbol)}
 	case 23: // a | b
-		return booleanArithmetic(words, storage, runtime.OR)
+		sym, err := endShortCircuit(words[0].(short_circuit), words[1].(variables.Symbol), storage, runtime.OR)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
 	case 25: // a & b
-		return booleanArithmetic(words, storage, runtime.AND)
+		sym, err := endShortCircuit(words[0].(short_circuit), words[1].(variables.Symbol), storage, runtime.AND)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 27: // !a
+		sym, err := doNot(words[1].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
 	case 29: // a == b
 		switch words[1].(string) {
 		case "==":
@@ -224,6 +1133,9 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 	case 40: // Declare new function, format "name ( arglist ) returntype"
 		arg_list := words[2].(List[variables.Argument]).Iterate()
 		ret_type := words[4].(variables.TypeDefinition)
+		if err := validateArgumentDeclarations(arg_list); err != nil {
+			log.Fatal(err)
+		}
 
 		def := variables.TypeDefinition{
 			BaseType:     variables.FUNC,
@@ -255,15 +1167,17 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 	case 45: //int type
 		return variables.TypeDefinition{BaseType: variables.INT}
 	case 46: // Function scope close
-		storage.LoadInstruction(&runtime.InstrExitFunction{})
-		storage.DestroyFunctionScope(r)
+		if err := doFunctionClose(storage, r); err != nil {
+			log.Fatal(err)
+		}
 	case 48: // If statement, NTIfHeader NTLabelledScopeBegin, NTStatementList, NTLabelledScopeClose
 		jmpIfInstr := words[0].(*runtime.InstrJmpIf)
 		instrEnd := words[3].(*runtime.InstructionLabelPair)
 		jmpIfInstr.Label = instrEnd.Label
 	case 49: //NTLabelledScopeBegin
-		instr := storage.LoadLabeledInstruction(&runtime.InstrBeginScope{}, storage.NewAutoLabel())
-		storage.NewScope()
+		label := storage.NewAutoLabel()
+		instr := storage.BeginScope()
+		instr.Label = label
 		return instr
 	case 50: //NTLabelledScopeClose
 		storage.LoadInstruction(&runtime.InstrEndScope{})
@@ -297,7 +1211,6 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 		}
 
 		tree_list := final_tree.Iterate()
-		fmt.Println(len(tree_list))
 		for i := range len(tree_list) - 1 {
 			// Make so all JumpIfs (which begins each conditional block) jump to the next condition
 			// Except the final one, which escapes the runtime
@@ -353,11 +1266,11 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 		storage.NewLabel(label)
 		return label
 	case 59: // arithmetic: modulo
-		return integerArithmetic(words, storage, runtime.MOD)
+		return arithmetic(words, storage, runtime.MOD)
 	case 60: // return Expr
-		storage.LoadInstruction(&runtime.InstrExitFunction{
-			RetVal: words[1].(variables.Symbol),
-		})
+		if err := doReturn(words[1].(variables.Symbol), storage); err != nil {
+			log.Fatal(err)
+		}
 	case 61: //NTVarType -> function (type_list) return_type
 		return_type := words[4].(variables.TypeDefinition)
 		type_list := words[2].(List[variables.TypeDefinition]).Iterate()
@@ -402,7 +1315,7 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 		storage.NewFunction(words[0].(string), def)
 		return def
 	case 66: // Call function, 0 arguments
-		var arg_list []variables.Symbol
+		var arg_list []call_argument
 		func_name := words[0].(string)
 		sym, err := doFunctionCall(func_name, arg_list, storage)
 		if err != nil {
@@ -414,6 +1327,9 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 	case 68: // New implicit function header "(arg_list) ret_type"
 		arg_list := words[1].(List[variables.Argument]).Iterate()
 		ret_type := words[3].(variables.TypeDefinition)
+		if err := validateArgumentDeclarations(arg_list); err != nil {
+			log.Fatal(err)
+		}
 
 		def := variables.TypeDefinition{
 			BaseType:     variables.FUNC,
@@ -421,6 +1337,538 @@ func DoActions(rule_id int, words []any, storage *storage.Storage, r *runtime.Ru
 			ReturnType:   &ret_type,
 		}
 		return storage.NewImplicitFunction(def)
+	case 69: // NTWhileBegin, label the start of the condition
+		start := storage.NewAutoLabel()
+		storage.LoadLabeledInstruction(&runtime.InstrNOP{}, start)
+		storage.BeginLoop(start)
+	case 70: // NTWhileHeader (while Expr)
+		condition := words[1].(variables.Symbol)
+		if condition.Type.BaseType != variables.BOOL {
+			log.Fatalln("Expected boolean statement in while clause, got", condition.Type)
+		}
+		storage.LoadInstruction(&runtime.InstrJmpIf{
+			Condition: condition,
+			Label:     storage.Loops.Peek().BreakLabel,
+		})
+	case 72: // End of loop body
+		storage.LoadInstruction(&runtime.InstrEndScope{})
+		storage.DestroyScope()
+		storage.EndLoop()
+	case 73: // break;
+		if err := storage.Break(); err != nil {
+			log.Fatal(err)
+		}
+	case 74: // continue;
+		if err := storage.Continue(); err != nil {
+			log.Fatal(err)
+		}
+	case 75: // NTForBegin, the loop variables live in their own scope
+		storage.BeginScope()
+	case 76: // NTForInit, label the start of the condition
+		start := storage.NewAutoLabel()
+		storage.LoadLabeledInstruction(&runtime.InstrNOP{}, start)
+		storage.BeginLoop(start)
+	case 77: // NTForCondition (Expr ;)
+		condition := words[1].(variables.Symbol)
+		if condition.Type.BaseType != variables.BOOL {
+			log.Fatalln("Expected boolean statement in for clause, got", condition.Type)
+		}
+		storage.LoadInstruction(&runtime.InstrJmpIf{
+			Condition: condition,
+			Label:     storage.Loops.Peek().BreakLabel,
+		})
+		// The post statement follows, which must be moved to after the loop body.
+		return storage.InstructionCount()
+	case 78: // NTForPost, assignment e.g. i = i + 1
+		addr, err := storage.GetAssignableAddr(words[0].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return doAssignment(words[2].(variables.Symbol), addr, storage)
+	case 79: // NTForHeader, NTForCondition NTForPost
+		storage.Loops.PeekRef().Post = storage.CutInstructions(words[0].(int))
+	case 80: // For loop, end the scope opened by NTForBegin
+		storage.LoadInstruction(&runtime.InstrEndScope{})
+		storage.DestroyScope()
+	case 81: //string type
+		return variables.TypeDefinition{BaseType: variables.STRING}
+	case 82: //New string literal
+		addr := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.STRING})
+		storage.LoadInstruction(&runtime.InstrLoadImmediate{
+			Dest:  addr,
+			Value: words[0].(string),
+		})
+		return addr
+	case 83: //float type
+		return variables.TypeDefinition{BaseType: variables.FLOAT}
+	case 84: //New float literal
+		value, err := floatval(words[0].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+		addr := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.FLOAT})
+		storage.LoadInstruction(&runtime.InstrLoadImmediate{
+			Dest:  addr,
+			Value: value,
+		})
+		return addr
+	case 85: // Dynamic array type "[]type"
+		element_type := words[2].(variables.TypeDefinition)
+		return variables.TypeDefinition{
+			BaseType:    variables.ARRAY,
+			ElementType: &element_type,
+		}
+	case 86: // Fixed size array type "[n]type"
+		element_type := words[3].(variables.TypeDefinition)
+		length, err := intval(words[1].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if length <= 0 {
+			log.Fatalf("invalid array length %d", length)
+		}
+		return variables.TypeDefinition{
+			BaseType:    variables.ARRAY,
+			ElementType: &element_type,
+			Length:      length,
+		}
+	case 88: // Array literal "type{ArgList}"
+		elements, err := positionalArguments(words[2].(List[call_argument]).Iterate())
+		if err != nil {
+			log.Fatal(err)
+		}
+		sym, err := doArrayLiteral(words[0].(variables.TypeDefinition), elements, storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 89: // Empty array literal "type{}"
+		sym, err := doArrayLiteral(words[0].(variables.TypeDefinition), nil, storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 90: // Read array element "a[i]" or map entry "m[key]"
+		array := words[0].(variables.Symbol)
+		index := words[2].(variables.Symbol)
+		if err := validateIndex(array, index); err != nil {
+			log.Fatal(err)
+		}
+
+		result := storage.NewLiteral(*array.Type.ElementType)
+		if array.Type.BaseType == variables.MAP {
+			storage.LoadInstruction(&runtime.InstrMapGet{
+				Map:    array,
+				Key:    index,
+				Zero:   array.Type.ElementType.ZeroValue(),
+				Result: result,
+			})
+			return result
+		}
+		storage.LoadInstruction(&runtime.InstrIndexGet{
+			Array:  array,
+			Index:  index,
+			Result: result,
+		})
+		return result
+	case 91: // Write array element "a[i] = Expr;" or map entry "m[key] = Expr;"
+		array := words[0].(variables.Symbol)
+		index := words[2].(variables.Symbol)
+		value := words[5].(variables.Symbol)
+		if err := validateIndex(array, index); err != nil {
+			log.Fatal(err)
+		}
+		if !value.Type.Equals(*array.Type.ElementType) {
+			log.Fatalf("invalid type assignment: expected %s, got %s", array.Type.ElementType, value.Type)
+		}
+		insertMapEntries(array, storage)
+
+		if array.Type.BaseType == variables.MAP {
+			storage.LoadInstruction(&runtime.InstrMapSet{
+				Map:   array,
+				Key:   index,
+				Value: value,
+			})
+			break
+		}
+		storage.LoadInstruction(&runtime.InstrIndexSet{
+			Array: array,
+			Index: index,
+			Value: value,
+		})
+	case 92: // len(Expr)
+		source := words[2].(variables.Symbol)
+		if source.Type.BaseType != variables.ARRAY && source.Type.BaseType != variables.MAP && source.Type.BaseType != variables.STRING {
+			log.Fatalf("invalid argument to len: %s", source.Type)
+		}
+
+		result := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.INT})
+		storage.LoadInstruction(&runtime.InstrLen{
+			Source: source,
+			Result: result,
+		})
+		return result
+	case 93: // append(Expr, ArgList)
+		values, err := positionalArguments(words[4].(List[call_argument]).Iterate())
+		if err != nil {
+			log.Fatal(err)
+		}
+		sym, err := doAppend(words[2].(variables.Symbol), values, storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 94: // Struct header "type name struct"
+		name := words[1].(string)
+		if err := storage.DeclareType(name); err != nil {
+			log.Fatal(err)
+		}
+		return name
+	case 95: // Struct declaration
+		name := words[0].(string)
+		fields := words[2].(List[variables.Argument]).Iterate()
+		for i := range fields {
+			for j := range i {
+				if fields[i].Identifier == fields[j].Identifier {
+					log.Fatalf("duplicate field %s in type %s", fields[i].Identifier, name)
+				}
+			}
+		}
+		storage.DefineType(name, variables.TypeDefinition{
+			BaseType: variables.STRUCT,
+			Name:     name,
+			Fields:   fields,
+		})
+	case 96: // Struct declaration, no fields
+		name := words[0].(string)
+		storage.DefineType(name, variables.TypeDefinition{
+			BaseType: variables.STRUCT,
+			Name:     name,
+		})
+	case 97: // Field declaration list, first element
+		second := words[1].(List[variables.Argument])
+		return List[variables.Argument]{
+			First:  words[0].(variables.Argument),
+			Second: &second,
+		}
+	case 98: // Field declaration list, final element
+		return List[variables.Argument]{
+			First:  words[0].(variables.Argument),
+			Second: nil,
+		}
+	case 99: // Field declaration "type name;"
+		return variables.Argument{
+			Definition: words[0].(variables.TypeDefinition),
+			Identifier: words[1].(string),
+		}
+	case 100: // Named type
+		_type, err := storage.GetType(words[0].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return _type
+	case 101: // Struct literal "name{field: Expr, ...}"
+		_type, err := storage.GetType(words[0].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+		sym, err := doStructLiteral(_type, words[2].(List[field_initializer]).Iterate(), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 102: // Struct literal "name{}"
+		_type, err := storage.GetType(words[0].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+		sym, err := doStructLiteral(_type, nil, storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 103: // Field initializer list, first element
+		second := words[2].(List[field_initializer])
+		return List[field_initializer]{
+			First:  words[0].(field_initializer),
+			Second: &second,
+		}
+	case 104: // Field initializer list, final element
+		return List[field_initializer]{
+			First:  words[0].(field_initializer),
+			Second: nil,
+		}
+	case 105: // Field initializer "field: Expr"
+		return field_initializer{
+			name:  words[0].(string),
+			value: words[2].(variables.Symbol),
+		}
+	case 106: // Read field "s.field"
+		value := words[0].(variables.Symbol)
+		index, field_type, err := validateField(value, words[2].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		result := storage.NewLiteral(field_type)
+		storage.LoadInstruction(&runtime.InstrFieldGet{
+			Struct: value,
+			Index:  index,
+			Result: result,
+		})
+		return result
+	case 107: // Write field "s.field = Expr;"
+		value := words[0].(variables.Symbol)
+		src := words[4].(variables.Symbol)
+		index, field_type, err := validateField(value, words[2].(string))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !src.Type.Equals(field_type) {
+			log.Fatalf("invalid type assignment: expected %s, got %s", field_type, src.Type)
+		}
+		insertMapEntries(value, storage)
+
+		storage.LoadInstruction(&runtime.InstrFieldSet{
+			Struct: value,
+			Index:  index,
+			Value:  src,
+		})
+	case 108: // Map type "map[key_type]value_type"
+		key_type := words[2].(variables.TypeDefinition)
+		value_type := words[4].(variables.TypeDefinition)
+		if !key_type.IsValidKey() {
+			log.Fatalf("invalid map key type %s, must be int, bool or string", key_type)
+		}
+		return variables.TypeDefinition{
+			BaseType:    variables.MAP,
+			KeyType:     &key_type,
+			ElementType: &value_type,
+		}
+	case 110: // Map literal "map[k]v{Expr: Expr, ...}"
+		sym, err := doMapLiteral(words[0].(variables.TypeDefinition), words[2].(List[map_entry]).Iterate(), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 111: // Empty map literal "map[k]v{}"
+		sym, err := doMapLiteral(words[0].(variables.TypeDefinition), nil, storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 112: // Map entry list, first element
+		second := words[2].(List[map_entry])
+		return List[map_entry]{
+			First:  words[0].(map_entry),
+			Second: &second,
+		}
+	case 113: // Map entry list, final element
+		return List[map_entry]{
+			First:  words[0].(map_entry),
+			Second: nil,
+		}
+	case 114: // Map entry "Expr: Expr"
+		return map_entry{
+			key:   words[0].(variables.Symbol),
+			value: words[2].(variables.Symbol),
+		}
+	case 115: // delete(map, key);
+		m := words[2].(variables.Symbol)
+		key := words[4].(variables.Symbol)
+		if err := validateMapKey(m, key); err != nil {
+			log.Fatal(err)
+		}
+		storage.LoadInstruction(&runtime.InstrMapDelete{
+			Map: m,
+			Key: key,
+		})
+	case 116: // has(map, key)
+		m := words[2].(variables.Symbol)
+		key := words[4].(variables.Symbol)
+		if err := validateMapKey(m, key); err != nil {
+			log.Fatal(err)
+		}
+		result := storage.NewLiteral(variables.TypeDefinition{BaseType: variables.BOOL})
+		storage.LoadInstruction(&runtime.InstrMapHas{
+			Map:    m,
+			Key:    key,
+			Result: result,
+		})
+		return result
+	case 117: // for key in Expr
+		if err := doForIn(words[1].(string), "", words[3].(variables.Symbol), storage); err != nil {
+			log.Fatal(err)
+		}
+	case 118: // for key, value in Expr
+		if err := doForIn(words[1].(string), words[3].(string), words[5].(variables.Symbol), storage); err != nil {
+			log.Fatal(err)
+		}
+	case 119: // For-in loop, end the scope opened by NTForBegin
+		storage.LoadInstruction(&runtime.InstrEndScope{})
+		storage.DestroyScope()
+	case 120: // New implicit function header, 0 arguments "() ret_type"
+		ret_type := words[2].(variables.TypeDefinition)
+		def := variables.TypeDefinition{
+			BaseType:   variables.FUNC,
+			ReturnType: &ret_type,
+		}
+		return storage.NewImplicitFunction(def)
+	case 121: // -a
+		sym, err := doNegate(words[1].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 123: // Left operand of a | b
+		sc, err := beginShortCircuit(words[0].(variables.Symbol), storage, runtime.OR)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sc
+	case 124: // Left operand of a & b
+		sc, err := beginShortCircuit(words[0].(variables.Symbol), storage, runtime.AND)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sc
+	case 125: // Compound assignment "a op= Expr"
+		sym, err := doCompoundAssignment(words[0].(string), words[2].(variables.Symbol), storage, assignmentOperator(words[1].(string)))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 126: // a++
+		sym, err := doIncrement(words[0].(string), storage, runtime.ADD)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 127: // a--
+		sym, err := doIncrement(words[0].(string), storage, runtime.SUB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 135: // Declaration with inferred type "identifier := Expr;"
+		sym, err := doInferredDeclaration(words[0].(string), words[2].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 136: // Start of constant declaration "const identifier ="
+		storage.BeginConstant(words[1].(string))
+	case 137: // Constant declaration "NTConstHeader Expr ;"
+		if err := storage.EndConstant(words[1].(variables.Symbol)); err != nil {
+			log.Fatal(err)
+		}
+	case 138: // void type
+		return variables.TypeDefinition{BaseType: variables.NONE}
+	case 139: // return;
+		if err := doVoidReturn(storage); err != nil {
+			log.Fatal(err)
+		}
+	case 140: // Variadic function argument declaration "type... identifier"
+		element_type := words[0].(variables.TypeDefinition)
+		return variables.Argument{
+			Definition: variables.TypeDefinition{BaseType: variables.ARRAY, ElementType: &element_type},
+			Identifier: words[2].(string),
+			Variadic:   true,
+		}
+	case 141: // Start of a default value "type identifier ="
+		storage.BeginConstant(words[1].(string))
+		return variables.Argument{
+			Definition: words[0].(variables.TypeDefinition),
+			Identifier: words[1].(string),
+		}
+	case 142: // Function argument declaration with a default value
+		arg, err := doDefaultArgument(words[0].(variables.Argument), words[1].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return arg
+	case 143: // Named argument "identifier : Expr"
+		return call_argument{Name: words[0].(string), Symbol: words[2].(variables.Symbol)}
+	case 144: // Switch header "switch Expr"
+		if err := doSwitchHeader(words[1].(variables.Symbol), storage); err != nil {
+			log.Fatal(err)
+		}
+	case 145: // Switch statement
+		storage.EndSwitch()
+	case 148, 149: // End of a case body
+		storage.LoadInstruction(&runtime.InstrEndScope{})
+		storage.DestroyScope()
+		storage.LoadInstruction(&runtime.InstrJmp{Label: storage.CurrentSwitch().EndLabel})
+	case 150: // Case header "case values :"
+		doCaseHeader(words[0].([]any), storage)
+	case 151: // Default case header "default :"
+		if err := doDefaultCase(storage); err != nil {
+			log.Fatal(err)
+		}
+	case 152: // Start of the first case value
+		storage.BeginCase()
+		storage.BeginConstant("case value")
+	case 153: // First case value
+		value, err := doCaseValue(words[1].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return []any{value}
+	case 154: // Following case values
+		value, err := doCaseValue(words[2].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return append(words[0].([]any), value)
+	case 155: // Start of a following case value
+		storage.BeginConstant("case value")
+	case 157: // Condition of a conditional expression "Expr ?"
+		ce, err := beginConditional(words[0].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return ce
+	case 158: // First branch of a conditional expression "Expr ? Expr :"
+		ce, err := doConditionalThen(words[0].(conditional_expression), words[1].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return ce
+	case 159: // Conditional expression "Expr ? Expr : Expr"
+		sym, err := endConditional(words[0].(conditional_expression), words[1].(variables.Symbol), storage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return sym
+	case 161: // Return type "( type_list )"
+		types := words[1].(List[variables.TypeDefinition]).Iterate()
+		if len(types) == 1 {
+			return types[0]
+		}
+		for _, _type := range types {
+			if _type.BaseType == variables.NONE {
+				log.Fatalf("void in the return type %s", variables.TypeDefinition{BaseType: variables.TUPLE, Elements: types})
+			}
+		}
+		return variables.TypeDefinition{BaseType: variables.TUPLE, Elements: types}
+	case 162: // Return multiple values "return Expr, Expr;"
+		if err := doMultipleReturn(words[1].([]variables.Symbol), storage); err != nil {
+			log.Fatal(err)
+		}
+	case 163: // Returned values, first two elements
+		return []variables.Symbol{words[0].(variables.Symbol), words[2].(variables.Symbol)}
+	case 164: // Returned values, following elements
+		return append(words[0].([]variables.Symbol), words[2].(variables.Symbol))
+	case 165: // Destructured variables, first two elements
+		return []string{words[0].(string), words[2].(string)}
+	case 166: // Destructured variables, following elements
+		return append(words[0].([]string), words[2].(string))
+	case 167, 168: // Destructuring "a, b := Expr;" and "a, b = Expr;"
+		err := doDestructuring(words[0].([]string), words[2].(variables.Symbol), words[1].(string) == ":=", storage)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 	return words[0]
 }
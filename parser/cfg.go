@@ -102,9 +102,9 @@ func CreateCFG() CFG {
 	cfg.addRule(tokens.NTNExpr, cfg_alternative{tokens.NTNExpr, tokens.ItemOpMinus, tokens.NTTerm}) // 5
 	cfg.addRule(tokens.NTNExpr, cfg_alternative{tokens.NTTerm})                                     // 6
 
-	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTTerm, tokens.ItemOpMult, tokens.NTFactor}) // 7
-	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTTerm, tokens.ItemOpDiv, tokens.NTFactor})  // 8
-	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTFactor})                                   // 9
+	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTTerm, tokens.ItemOpMult, tokens.NTUnary}) // 7
+	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTTerm, tokens.ItemOpDiv, tokens.NTUnary})  // 8
+	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTUnary})                                   // 9
 
 	cfg.addRules(tokens.NTFactor, []cfg_alternative{
 		{tokens.ItemParOpen, tokens.NTExpr, tokens.ItemParClosed}, // 10
@@ -118,7 +118,7 @@ func CreateCFG() CFG {
 		{tokens.NTExpr, tokens.ItemSemicolon},                                                             // 15
 	})
 
-	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTScopeBegin, tokens.NTStatement, tokens.NTScopeClose}) // 16
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTScopeBegin, tokens.NTStatementList, tokens.NTScopeClose}) // 16
 
 	cfg.addRule(tokens.NTScopeBegin, cfg_alternative{tokens.ItemScopeOpen})
 	cfg.addRule(tokens.NTScopeClose, cfg_alternative{tokens.ItemScopeClose})
@@ -130,16 +130,16 @@ func CreateCFG() CFG {
 
 	cfg.addRule(tokens.NTArgument, cfg_alternative{tokens.NTExpr})
 
-	cfg.addRules(tokens.NTExpr, []cfg_alternative{
-		{tokens.NTExpr, tokens.ItemBoolOr, tokens.NTAndTerm},
+	cfg.addRules(tokens.NTOrExpr, []cfg_alternative{
+		{tokens.NTOrLeft, tokens.NTAndTerm},
 		{tokens.NTAndTerm},
 	})
 	cfg.addRules(tokens.NTAndTerm, []cfg_alternative{
-		{tokens.NTAndTerm, tokens.ItemBoolAnd, tokens.NTNotTerm},
+		{tokens.NTAndLeft, tokens.NTNotTerm},
 		{tokens.NTNotTerm},
 	})
 	cfg.addRules(tokens.NTNotTerm, []cfg_alternative{
-		{tokens.ItemBoolNot, tokens.NTRelExpr},
+		{tokens.ItemBoolNot, tokens.NTNotTerm},
 		{tokens.NTRelExpr},
 	})
 	cfg.addRules(tokens.NTRelExpr, []cfg_alternative{
@@ -167,7 +167,7 @@ func CreateCFG() CFG {
 		cfg_alternative{tokens.ItemFunction, tokens.NTFunctionDefinition, tokens.NTFunctionBody}) //39
 
 	cfg.addRule(tokens.NTFunctionDefinition,
-		cfg_alternative{tokens.ItemIdentifier, tokens.ItemParOpen, tokens.NTArgumentDeclarationList, tokens.ItemParClosed, tokens.NTVarType}) //40
+		cfg_alternative{tokens.ItemIdentifier, tokens.ItemParOpen, tokens.NTArgumentDeclarationList, tokens.ItemParClosed, tokens.NTReturnType}) //40
 
 	cfg.addRules(tokens.NTArgumentDeclarationList, []cfg_alternative{
 		{tokens.NTArgumentDeclaration, tokens.ItemComma, tokens.NTArgumentDeclarationList}, //41
@@ -209,25 +209,224 @@ func CreateCFG() CFG {
 	cfg.addRule(tokens.NTWithElse, cfg_alternative{tokens.ItemElse, tokens.NTLabelledScopeBegin, tokens.NTStatementList, tokens.NTLabelledScopeClose}) // 56
 	cfg.addRule(tokens.NTEndConditionalScope, cfg_alternative{tokens.NTScopeClose})                                                                    // 57
 	cfg.addRule(tokens.NTBeginElseIf, cfg_alternative{tokens.ItemElse})                                                                                //58
-	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTTerm, tokens.ItemOpMod, tokens.NTFactor})                                                      // 59
+	cfg.addRule(tokens.NTTerm, cfg_alternative{tokens.NTTerm, tokens.ItemOpMod, tokens.NTUnary})                                                       // 59
 	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemReturn, tokens.NTExpr, tokens.ItemSemicolon})                                           // 60
 	//61
-	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemFunction, tokens.ItemParOpen, tokens.NTTypeList, tokens.ItemParClosed, tokens.NTVarType})
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemFunction, tokens.ItemParOpen, tokens.NTTypeList, tokens.ItemParClosed, tokens.NTReturnType})
 	//62
 	cfg.addRule(tokens.NTTypeList, cfg_alternative{tokens.NTVarType, tokens.ItemComma, tokens.NTTypeList})
 	//63
 	cfg.addRule(tokens.NTTypeList, cfg_alternative{tokens.NTVarType})
 	//64 - Declare type, empty argument list
-	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemFunction, tokens.ItemParOpen, tokens.ItemParClosed, tokens.NTVarType})
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemFunction, tokens.ItemParOpen, tokens.ItemParClosed, tokens.NTReturnType})
 	//65 - Function definition, no arguments
 	cfg.addRule(tokens.NTFunctionDefinition,
-		cfg_alternative{tokens.ItemIdentifier, tokens.ItemParOpen, tokens.ItemParClosed, tokens.NTVarType})
+		cfg_alternative{tokens.ItemIdentifier, tokens.ItemParOpen, tokens.ItemParClosed, tokens.NTReturnType})
 	//66 - Function call, no arguments
 	cfg.addRule(tokens.NTFunctionCall, cfg_alternative{tokens.ItemIdentifier, tokens.ItemParOpen, tokens.ItemParClosed})
 	//67 - Implicit function definition
 	cfg.addRule(tokens.NTExpr, cfg_alternative{tokens.NTImplicitFunctionDefinition, tokens.NTFunctionBody})
 	//68 - Implicit function definition header
-	cfg.addRule(tokens.NTImplicitFunctionDefinition, cfg_alternative{tokens.ItemParOpen, tokens.NTArgumentDeclarationList, tokens.ItemParClosed, tokens.NTVarType})
+	cfg.addRule(tokens.NTImplicitFunctionDefinition, cfg_alternative{tokens.ItemParOpen, tokens.NTArgumentDeclarationList, tokens.ItemParClosed, tokens.NTReturnType})
+	//69 - Start of while loop, labels the first instruction of the condition
+	cfg.addRule(tokens.NTWhileBegin, cfg_alternative{tokens.ItemWhile})
+	//70 - While header "while Expr"
+	cfg.addRule(tokens.NTWhileHeader, cfg_alternative{tokens.NTWhileBegin, tokens.NTExpr})
+	//71 - While loop
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTWhileHeader, tokens.NTScopeBegin, tokens.NTStatementList, tokens.NTLoopScopeClose})
+	//72 - End of loop body
+	cfg.addRule(tokens.NTLoopScopeClose, cfg_alternative{tokens.ItemScopeClose})
+	//73 - break;
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemBreak, tokens.ItemSemicolon})
+	//74 - continue;
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemContinue, tokens.ItemSemicolon})
+	//75 - Start of for loop, opens the scope holding the loop variables
+	cfg.addRule(tokens.NTForBegin, cfg_alternative{tokens.ItemFor})
+	//76 - For loop initial statement, e.g. "for int i = 0;"
+	cfg.addRule(tokens.NTForInit, cfg_alternative{tokens.NTForBegin, tokens.NTStatement})
+	//77 - For loop condition "Expr ;"
+	cfg.addRule(tokens.NTForCondition, cfg_alternative{tokens.NTForInit, tokens.NTExpr, tokens.ItemSemicolon})
+	//78 - For loop post statement, e.g. "i = i + 1"
+	cfg.addRule(tokens.NTForPost, cfg_alternative{tokens.ItemIdentifier, tokens.ItemEquals, tokens.NTExpr})
+	//79 - Complete for loop header
+	cfg.addRule(tokens.NTForHeader, cfg_alternative{tokens.NTForCondition, tokens.NTForPost})
+	//80 - For loop
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTForHeader, tokens.NTScopeBegin, tokens.NTStatementList, tokens.NTLoopScopeClose})
+	//81 - string type
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemKeyString})
+	//82 - string literal
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemText})
+	//83 - float type
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemKeyFloat})
+	//84 - float literal
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemFloat})
+	//85 - Dynamic array type "[]type"
+	cfg.addRule(tokens.NTArrayType, cfg_alternative{tokens.ItemBracketOpen, tokens.ItemBracketClose, tokens.NTVarType})
+	//86 - Fixed size array type "[n]type"
+	cfg.addRule(tokens.NTArrayType, cfg_alternative{tokens.ItemBracketOpen, tokens.ItemNumber, tokens.ItemBracketClose, tokens.NTVarType})
+	//87
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.NTArrayType})
+	//88 - Array literal, e.g. "[]int{1, 2, 3}"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.NTArrayType, tokens.ItemScopeOpen, tokens.NTArgList, tokens.ItemScopeClose})
+	//89 - Empty array literal "[]int{}"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.NTArrayType, tokens.ItemScopeOpen, tokens.ItemScopeClose})
+	//90 - Read array element "a[i]"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.NTFactor, tokens.ItemBracketOpen, tokens.NTExpr, tokens.ItemBracketClose})
+	//91 - Write array element "a[i] = Expr;"
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTFactor, tokens.ItemBracketOpen, tokens.NTExpr, tokens.ItemBracketClose,
+		tokens.ItemEquals, tokens.NTExpr, tokens.ItemSemicolon})
+	//92 - len(Expr)
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemLen, tokens.ItemParOpen, tokens.NTExpr, tokens.ItemParClosed})
+	//93 - append(Expr, ArgList)
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemAppend, tokens.ItemParOpen, tokens.NTExpr, tokens.ItemComma, tokens.NTArgList, tokens.ItemParClosed})
+	//94 - Struct header "type name struct", declares the type name
+	cfg.addRule(tokens.NTStructHeader, cfg_alternative{tokens.ItemKeyType, tokens.ItemIdentifier, tokens.ItemStruct})
+	//95 - Struct declaration
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTStructHeader, tokens.ItemScopeOpen, tokens.NTFieldDeclarationList, tokens.ItemScopeClose})
+	//96 - Struct declaration, no fields
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTStructHeader, tokens.ItemScopeOpen, tokens.ItemScopeClose})
+	//97 - Field declaration list, first element
+	cfg.addRule(tokens.NTFieldDeclarationList, cfg_alternative{tokens.NTFieldDeclaration, tokens.NTFieldDeclarationList})
+	//98 - Field declaration list, final element
+	cfg.addRule(tokens.NTFieldDeclarationList, cfg_alternative{tokens.NTFieldDeclaration})
+	//99 - Field declaration "type name;"
+	cfg.addRule(tokens.NTFieldDeclaration, cfg_alternative{tokens.NTVarType, tokens.ItemIdentifier, tokens.ItemSemicolon})
+	//100 - Named type
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemTypeName})
+	//101 - Struct literal "name{field: Expr, ...}"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemTypeName, tokens.ItemScopeOpen, tokens.NTFieldInitList, tokens.ItemScopeClose})
+	//102 - Struct literal with all fields set to their zero value "name{}"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemTypeName, tokens.ItemScopeOpen, tokens.ItemScopeClose})
+	//103 - Field initializer list, first element
+	cfg.addRule(tokens.NTFieldInitList, cfg_alternative{tokens.NTFieldInit, tokens.ItemComma, tokens.NTFieldInitList})
+	//104 - Field initializer list, final element
+	cfg.addRule(tokens.NTFieldInitList, cfg_alternative{tokens.NTFieldInit})
+	//105 - Field initializer "field: Expr"
+	cfg.addRule(tokens.NTFieldInit, cfg_alternative{tokens.ItemIdentifier, tokens.ItemColon, tokens.NTExpr})
+	//106 - Read field "s.field"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.NTFactor, tokens.ItemDot, tokens.ItemIdentifier})
+	//107 - Write field "s.field = Expr;"
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTFactor, tokens.ItemDot, tokens.ItemIdentifier, tokens.ItemEquals, tokens.NTExpr, tokens.ItemSemicolon})
+	//108 - Map type "map[key_type]value_type"
+	cfg.addRule(tokens.NTMapType, cfg_alternative{tokens.ItemMap, tokens.ItemBracketOpen, tokens.NTVarType, tokens.ItemBracketClose, tokens.NTVarType})
+	//109
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.NTMapType})
+	//110 - Map literal "map[k]v{Expr: Expr, ...}"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.NTMapType, tokens.ItemScopeOpen, tokens.NTMapInitList, tokens.ItemScopeClose})
+	//111 - Empty map literal "map[k]v{}"
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.NTMapType, tokens.ItemScopeOpen, tokens.ItemScopeClose})
+	//112 - Map entry list, first element
+	cfg.addRule(tokens.NTMapInitList, cfg_alternative{tokens.NTMapInit, tokens.ItemComma, tokens.NTMapInitList})
+	//113 - Map entry list, final element
+	cfg.addRule(tokens.NTMapInitList, cfg_alternative{tokens.NTMapInit})
+	//114 - Map entry "Expr: Expr"
+	cfg.addRule(tokens.NTMapInit, cfg_alternative{tokens.NTExpr, tokens.ItemColon, tokens.NTExpr})
+	//115 - delete(map, key);
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemDelete, tokens.ItemParOpen, tokens.NTExpr, tokens.ItemComma, tokens.NTExpr, tokens.ItemParClosed, tokens.ItemSemicolon})
+	//116 - has(map, key)
+	cfg.addRule(tokens.NTFactor, cfg_alternative{tokens.ItemHas, tokens.ItemParOpen, tokens.NTExpr, tokens.ItemComma, tokens.NTExpr, tokens.ItemParClosed})
+	//117 - Iterate over map keys "for key in Expr"
+	cfg.addRule(tokens.NTForInHeader, cfg_alternative{tokens.NTForBegin, tokens.ItemIdentifier, tokens.ItemIn, tokens.NTExpr})
+	//118 - Iterate over map keys and values "for key, value in Expr"
+	cfg.addRule(tokens.NTForInHeader, cfg_alternative{tokens.NTForBegin, tokens.ItemIdentifier, tokens.ItemComma, tokens.ItemIdentifier, tokens.ItemIn, tokens.NTExpr})
+	//119 - For-in loop
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTForInHeader, tokens.NTScopeBegin, tokens.NTStatementList, tokens.NTLoopScopeClose})
+	//120 - Implicit function definition header, no arguments
+	cfg.addRule(tokens.NTImplicitFunctionDefinition, cfg_alternative{tokens.ItemParOpen, tokens.ItemParClosed, tokens.NTReturnType})
+	//121 - Unary minus, binds tighter than the multiplicative operators
+	cfg.addRule(tokens.NTUnary, cfg_alternative{tokens.ItemOpMinus, tokens.NTUnary})
+	//122
+	cfg.addRule(tokens.NTUnary, cfg_alternative{tokens.NTFactor})
+	//123 - Left operand of |, evaluated before the right operand is
+	cfg.addRule(tokens.NTOrLeft, cfg_alternative{tokens.NTOrExpr, tokens.ItemBoolOr})
+	//124 - Left operand of &
+	cfg.addRule(tokens.NTAndLeft, cfg_alternative{tokens.NTAndTerm, tokens.ItemBoolAnd})
+	//125 - Compound assignment, e.g. a += 2
+	cfg.addRule(tokens.NTUpdate, cfg_alternative{tokens.ItemIdentifier, tokens.NTAssignOp, tokens.NTExpr})
+	//126 - a++
+	cfg.addRule(tokens.NTUpdate, cfg_alternative{tokens.ItemIdentifier, tokens.ItemIncrement})
+	//127 - a--
+	cfg.addRule(tokens.NTUpdate, cfg_alternative{tokens.ItemIdentifier, tokens.ItemDecrement})
+	//128
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTUpdate, tokens.ItemSemicolon})
+	//129 - Post statement of a for loop, e.g. i++
+	cfg.addRule(tokens.NTForPost, cfg_alternative{tokens.NTUpdate})
+	//130-134
+	cfg.addRules(tokens.NTAssignOp, []cfg_alternative{
+		{tokens.ItemPlusEquals},
+		{tokens.ItemMinusEquals},
+		{tokens.ItemMultEquals},
+		{tokens.ItemDivEquals},
+		{tokens.ItemModEquals},
+	})
+	//135 - Declaration with the type of the expression, e.g. a := 3
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemIdentifier, tokens.ItemDeclare, tokens.NTExpr, tokens.ItemSemicolon})
+	//136 - Start of a constant declaration "const identifier ="
+	cfg.addRule(tokens.NTConstHeader, cfg_alternative{tokens.ItemConst, tokens.ItemIdentifier, tokens.ItemEquals})
+	//137 - Constant declaration, e.g. const FLOORS = 4;
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTConstHeader, tokens.NTExpr, tokens.ItemSemicolon})
+	//138 - void return type
+	cfg.addRule(tokens.NTVarType, cfg_alternative{tokens.ItemVoid})
+	//139 - Return from a void function, "return;"
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemReturn, tokens.ItemSemicolon})
+	//140 - Variadic function argument declaration, e.g. int... values
+	cfg.addRule(tokens.NTArgumentDeclaration, cfg_alternative{tokens.NTVarType, tokens.ItemEllipsis, tokens.ItemIdentifier})
+	//141 - Start of a function argument with a default value "type identifier ="
+	cfg.addRule(tokens.NTDefaultHeader, cfg_alternative{tokens.NTVarType, tokens.ItemIdentifier, tokens.ItemEquals})
+	//142 - Function argument declaration with a default value, e.g. int timeout = 5000
+	cfg.addRule(tokens.NTArgumentDeclaration, cfg_alternative{tokens.NTDefaultHeader, tokens.NTExpr})
+	//143 - Named argument in a function call, e.g. timeout: 10000
+	cfg.addRule(tokens.NTArgument, cfg_alternative{tokens.ItemIdentifier, tokens.ItemColon, tokens.NTExpr})
+	//144 - Switch header "switch Expr"
+	cfg.addRule(tokens.NTSwitchHeader, cfg_alternative{tokens.ItemSwitch, tokens.NTExpr})
+	//145 - Switch statement
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTSwitchHeader, tokens.ItemScopeOpen, tokens.NTCaseList, tokens.ItemScopeClose})
+	//146 - Case list, first element
+	cfg.addRule(tokens.NTCaseList, cfg_alternative{tokens.NTCase, tokens.NTCaseList})
+	//147 - Case list, final element
+	cfg.addRule(tokens.NTCaseList, cfg_alternative{tokens.NTCase})
+	//148 - Case with a body
+	cfg.addRule(tokens.NTCase, cfg_alternative{tokens.NTCaseHeader, tokens.NTStatementList})
+	//149 - Case with an empty body
+	cfg.addRule(tokens.NTCase, cfg_alternative{tokens.NTCaseHeader})
+	//150 - Case header "case values :"
+	cfg.addRule(tokens.NTCaseHeader, cfg_alternative{tokens.NTCaseValues, tokens.ItemColon})
+	//151 - Default case header "default :"
+	cfg.addRule(tokens.NTCaseHeader, cfg_alternative{tokens.ItemDefault, tokens.ItemColon})
+	//152 - Start of the first case value "case"
+	cfg.addRule(tokens.NTCaseBegin, cfg_alternative{tokens.ItemCase})
+	//153 - First case value
+	cfg.addRule(tokens.NTCaseValues, cfg_alternative{tokens.NTCaseBegin, tokens.NTExpr})
+	//154 - Following case values, e.g. case 1, 2
+	cfg.addRule(tokens.NTCaseValues, cfg_alternative{tokens.NTCaseValues, tokens.NTCaseSeparator, tokens.NTExpr})
+	//155 - Start of a following case value ","
+	cfg.addRule(tokens.NTCaseSeparator, cfg_alternative{tokens.ItemComma})
+	//156
+	cfg.addRule(tokens.NTExpr, cfg_alternative{tokens.NTOrExpr})
+	//157 - Condition of a conditional expression "Expr ?"
+	cfg.addRule(tokens.NTConditionalHeader, cfg_alternative{tokens.NTOrExpr, tokens.ItemQuestion})
+	//158 - First branch of a conditional expression "Expr ? Expr :"
+	cfg.addRule(tokens.NTConditionalThen, cfg_alternative{tokens.NTConditionalHeader, tokens.NTExpr, tokens.ItemColon})
+	//159 - Conditional expression, e.g. floor > target ? DOWN : UP
+	cfg.addRule(tokens.NTExpr, cfg_alternative{tokens.NTConditionalThen, tokens.NTExpr})
+	//160 - Return type of a function returning a single value
+	cfg.addRule(tokens.NTReturnType, cfg_alternative{tokens.NTVarType})
+	//161 - Return type of a function returning multiple values, e.g. (int, bool)
+	cfg.addRule(tokens.NTReturnType, cfg_alternative{tokens.ItemParOpen, tokens.NTTypeList, tokens.ItemParClosed})
+	//162 - Return multiple values "return Expr, Expr;"
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.ItemReturn, tokens.NTReturnValues, tokens.ItemSemicolon})
+	//163 - Returned values, first two elements
+	cfg.addRule(tokens.NTReturnValues, cfg_alternative{tokens.NTExpr, tokens.ItemComma, tokens.NTExpr})
+	//164 - Returned values, following elements
+	cfg.addRule(tokens.NTReturnValues, cfg_alternative{tokens.NTReturnValues, tokens.ItemComma, tokens.NTExpr})
+	//165 - Destructured variables, first two elements
+	cfg.addRule(tokens.NTIdentList, cfg_alternative{tokens.ItemIdentifier, tokens.ItemComma, tokens.ItemIdentifier})
+	//166 - Destructured variables, following elements
+	cfg.addRule(tokens.NTIdentList, cfg_alternative{tokens.NTIdentList, tokens.ItemComma, tokens.ItemIdentifier})
+	//167 - Declare variables from multiple values, e.g. floor, direction := status(2);
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTIdentList, tokens.ItemDeclare, tokens.NTExpr, tokens.ItemSemicolon})
+	//168 - Assign multiple values, e.g. floor, direction = status(2);
+	cfg.addRule(tokens.NTStatement, cfg_alternative{tokens.NTIdentList, tokens.ItemEquals, tokens.NTExpr, tokens.ItemSemicolon})
 	fmt.Println("Num rules: ", len(cfg._array))
 	cfg.compile()
 
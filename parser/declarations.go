@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"dsl/runtime"
+	"dsl/storage"
+	"dsl/tokens"
+)
+
+// Functions in the outermost scope can be called before they are defined. Before the program is parsed,
+// the headers of these functions are parsed together with the struct types and constants they may refer to,
+// and the types, constants and functions are declared in target.
+// Variables of the outermost scope are not declared ahead, see storage.DeclareUpcoming.
+func (parser *LRParser) DeclareFunctions(words []tokens.Token, cfg CFG, grammar tokens.Grammar, target *storage.Storage) error {
+	declarations, globals := collectDeclarations(words)
+	target.DeclareUpcoming(globals)
+	if declarations == nil {
+		return nil
+	}
+
+	stream := make(chan tokens.Token, len(declarations))
+	for i := range declarations {
+		stream <- declarations[i]
+	}
+	close(stream)
+
+	scratch := storage.NewStorage()
+	scratch.Headers = true
+	scratch.DeclareUpcoming(globals)
+	if _, _, err := parser.Parse(stream, cfg, grammar, &scratch, runtime.New()); err != nil {
+		return err
+	}
+	for name, definition := range scratch.Types {
+		target.DeclareTypeAhead(name, definition)
+	}
+	for name, entry := range scratch.GlobalConstants() {
+		target.DeclareConstantAhead(name, entry)
+	}
+	for _, function := range scratch.Functions() {
+		if err := target.DeclareFunction(function.Name, function.Definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extract the declarations of the outermost scope from the program: struct types, constants and functions.
+// Types and constants are placed before the functions, which may use them regardless of where they are declared.
+// Function bodies are replaced by a statement that does nothing. Returns nil if there are no functions.
+// Also returns the names of the variables declared in the outermost scope.
+func collectDeclarations(words []tokens.Token) ([]tokens.Token, []string) {
+	var declarations, headers []tokens.Token
+	var globals []string
+	functions := 0
+	depth, parentheses := 0, 0
+	for i := 0; i < len(words); i++ {
+		switch words[i].Category {
+		case tokens.ItemScopeOpen:
+			depth += 1
+		case tokens.ItemScopeClose:
+			depth -= 1
+		}
+		if depth > 0 {
+			continue
+		}
+
+		switch {
+		case words[i].Category == tokens.ItemParOpen:
+			parentheses += 1
+		case words[i].Category == tokens.ItemParClosed:
+			parentheses -= 1
+		case words[i].Category == tokens.ItemFor:
+			// The variable declared by a for loop is local to the loop
+			for i+1 < len(words) && words[i+1].Category != tokens.ItemScopeOpen {
+				i++
+			}
+		case words[i].Category == tokens.ItemDeclare:
+			// "a := Expr;" or "a, b := Expr;"
+			for j := i - 1; j >= 0 && words[j].Category == tokens.ItemIdentifier; j -= 2 {
+				globals = append(globals, words[j].Lexeme)
+				if j == 0 || words[j-1].Category != tokens.ItemComma {
+					break
+				}
+			}
+		case words[i].Category == tokens.ItemEquals && parentheses == 0 && i >= 2 &&
+			words[i-1].Category == tokens.ItemIdentifier && endsType(words[i-2]):
+			// "type a = Expr;"
+			globals = append(globals, words[i-1].Lexeme)
+		case words[i].Category == tokens.ItemKeyType:
+			end := declarationEnd(words, i, tokens.ItemScopeClose)
+			declarations = append(declarations, words[i:end]...)
+			i = end - 1
+		case words[i].Category == tokens.ItemConst:
+			end := declarationEnd(words, i, tokens.ItemSemicolon)
+			declarations = append(declarations, words[i:end]...)
+			i = end - 1
+		case words[i].Category == tokens.ItemFunction && i+1 < len(words) && words[i+1].Category == tokens.ItemIdentifier:
+			// The body is the first scope outside of the parentheses, default values may contain scopes
+			body, parentheses := i, 0
+			for ; body < len(words); body++ {
+				if words[body].Category == tokens.ItemParOpen {
+					parentheses += 1
+				} else if words[body].Category == tokens.ItemParClosed {
+					parentheses -= 1
+				} else if words[body].Category == tokens.ItemScopeOpen && parentheses == 0 {
+					break
+				}
+			}
+			headers = append(headers, words[i:body]...)
+			headers = append(headers,
+				tokens.Token{Category: tokens.ItemScopeOpen, Lexeme: "{"},
+				tokens.Token{Category: tokens.ItemNumber, Lexeme: "0"},
+				tokens.Token{Category: tokens.ItemSemicolon, Lexeme: ";"},
+				tokens.Token{Category: tokens.ItemScopeClose, Lexeme: "}"})
+			i = declarationEnd(words, body, tokens.ItemScopeClose) - 1
+			functions += 1
+		}
+	}
+
+	if functions == 0 {
+		return nil, globals
+	}
+	declarations = append(declarations, headers...)
+	return append(declarations, tokens.Token{Category: tokens.ItemEOF}), globals
+}
+
+// Whether a type may end with word, e.g. the name of a struct type or the return type of a function type.
+func endsType(word tokens.Token) bool {
+	switch word.Category {
+	case tokens.ItemKeyInt, tokens.ItemKeyBool, tokens.ItemKeyString, tokens.ItemKeyFloat,
+		tokens.ItemIdentifier, tokens.ItemVoid, tokens.ItemParClosed:
+		return true
+	}
+	return false
+}
+
+// The index after the first closing token outside of any scope, starting at start.
+func declarationEnd(words []tokens.Token, start int, closing tokens.ItemType) int {
+	depth := 0
+	for i := start; i < len(words); i++ {
+		switch words[i].Category {
+		case tokens.ItemScopeOpen:
+			depth += 1
+		case tokens.ItemScopeClose:
+			depth -= 1
+		}
+		if depth == 0 && words[i].Category == closing {
+			return i + 1
+		}
+	}
+	return len(words)
+}
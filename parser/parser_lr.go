@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type closure_item struct {
@@ -18,7 +19,7 @@ type closure_item struct {
 }
 
 func (c closure_item) hash() string {
-	return fmt.Sprintf("%s %s %s", strconv.Itoa(c.rule_id), strconv.Itoa(c.dot_pos), strconv.Itoa(int(c.lookahead)))
+	return strconv.Itoa(c.rule_id) + " " + strconv.Itoa(c.dot_pos) + " " + strconv.Itoa(int(c.lookahead))
 }
 
 type closure struct {
@@ -62,6 +63,21 @@ func (c *closure) compare(other *closure) bool {
 	return reflect.DeepEqual(c.hashTable, other.hashTable) // May be possible to optimize?
 }
 
+// A string that is equal for two closures if and only if they contain the same items.
+// Used to look up closures in a map, rather than comparing against every closure.
+func (c *closure) key() string {
+	return strings.Join(c.hashTable.SortedList(), ";")
+}
+
+// Find the index of the closure with the same items as c, or -1 if there is none.
+func findClosure(index map[string]int, c *closure) int {
+	idx, ok := index[c.key()]
+	if !ok {
+		return -1
+	}
+	return idx
+}
+
 func makeClosure(closure closure, cfg CFG, first FirstSet) closure {
 	oldHashSize := -1
 	for oldHashSize != closure.hashTable.Size() {
@@ -111,7 +127,13 @@ func makeGoto(closure closure, cfg CFG, first FirstSet, x tokens.ItemType) closu
 	return ret
 }
 
-func computeClosures(grammar tokens.Grammar, cfg CFG, first FirstSet) []closure {
+type goto_key struct {
+	x       tokens.ItemType
+	closure int
+}
+
+// Compute the canonical collection of closures, along with the goto transitions between them.
+func computeClosures(grammar tokens.Grammar, cfg CFG, first FirstSet) ([]closure, map[goto_key]int) {
 	initial_closure := newClosure()
 
 	for rule_id := range cfg.GetRuleIndexesForA(grammar.StartSymbol) {
@@ -125,6 +147,8 @@ func computeClosures(grammar tokens.Grammar, cfg CFG, first FirstSet) []closure
 
 	cc0 := makeClosure(initial_closure, cfg, first)
 	cc_list := []closure{cc0}
+	cc_index_by_key := map[string]int{cc0.key(): 0}
+	transitions := make(map[goto_key]int)
 
 	cc_index := 0
 	prev_cc_size := 0
@@ -142,22 +166,19 @@ func computeClosures(grammar tokens.Grammar, cfg CFG, first FirstSet) []closure
 
 			for _, x := range follows_dot.SortedList() {
 				temp := makeGoto(cc_list[cc_index], cfg, first, x)
-				alreadyExists := false
-				for cc_indexj := range cc_list {
-					if temp.compare(&cc_list[cc_indexj]) {
-						alreadyExists = true
-						break
-					}
-				}
 
-				if !alreadyExists {
+				cc_j := findClosure(cc_index_by_key, &temp)
+				if cc_j < 0 {
+					cc_j = len(cc_list)
+					cc_index_by_key[temp.key()] = cc_j
 					cc_list = append(cc_list, temp)
 				}
+				transitions[goto_key{x: x, closure: cc_index}] = cc_j
 			}
 		}
 	}
 
-	return cc_list
+	return cc_list, transitions
 }
 
 type ActionType int
@@ -182,32 +203,17 @@ type LRParser struct {
 }
 
 func CreateLRParser(grammar tokens.Grammar, cfg CFG, first FirstSet) LRParser {
-	closures := computeClosures(grammar, cfg, first)
-
-	type goto_key struct {
-		x       tokens.ItemType
-		closure int
-	}
+	closures, transitions := computeClosures(grammar, cfg, first)
 
 	actionTable := make(ActionTable, len(closures))
 	gotoTable := make(GotoTable, len(closures))
 
-	goto_cache := make(map[goto_key]int)
 	_goto := func(i int, x tokens.ItemType) int {
-		goto_key := goto_key{closure: i, x: x}
-		_, ok := goto_cache[goto_key]
+		cc_j, ok := transitions[goto_key{closure: i, x: x}]
 		if !ok {
-			tmp := makeGoto(closures[i], cfg, first, goto_key.x)
-			val := -1
-			for idx := range closures {
-				if tmp.compare(&closures[idx]) {
-					val = idx
-					break
-				}
-			}
-			goto_cache[goto_key] = val
+			return -1
 		}
-		return goto_cache[goto_key]
+		return cc_j
 	}
 
 	for i := range len(closures) {
@@ -272,7 +278,7 @@ func CreateLRParser(grammar tokens.Grammar, cfg CFG, first FirstSet) LRParser {
 }
 
 func (parser *LRParser) Parse(words <-chan tokens.Token, cfg CFG, grammar tokens.Grammar,
-	storage *storage.Storage, runtime *runtime.Runtime) (int, error) {
+	storage *storage.Storage, runtime *runtime.Runtime) (entryPoint int, frameSize int, err error) {
 	type stack_state struct {
 		symbol tokens.ItemType
 		state  int
@@ -286,7 +292,20 @@ func (parser *LRParser) Parse(words <-chan tokens.Token, cfg CFG, grammar tokens
 	actionTable := parser.ActionTable
 	gotoTable := parser.GotoTable
 
-	word := <-words
+	// The scanner cannot tell type names from other identifiers, so identifiers naming a declared type are
+	// recategorized here. A struct's name is declared when its header is reduced, before its body is read.
+	// The name in a header is left as is, since the type may have been declared ahead, see DeclareFunctions.
+	previous := tokens.ItemError
+	nextWord := func() tokens.Token {
+		word := <-words
+		if word.Category == tokens.ItemIdentifier && previous != tokens.ItemKeyType && storage.IsTypeName(word.Lexeme) {
+			word.Category = tokens.ItemTypeName
+		}
+		previous = word.Category
+		return word
+	}
+
+	word := nextWord()
 
 	for {
 		state := stack.Peek()
@@ -307,21 +326,23 @@ func (parser *LRParser) Parse(words <-chan tokens.Token, cfg CFG, grammar tokens
 			state = stack.Peek()
 			_goto := gotoTable[state.state][grammar.MapToArrayindex(rule.A)]
 			if _goto < 0 {
-				return 0, errors.New("bad goto")
+				return 0, 0, errors.New("bad goto")
 			}
 			stack.Push(stack_state{rule.A, _goto, value})
 		case ACTION_SHIFT:
 			stack.Push(stack_state{word.Category, action.Value, word.Lexeme})
-			word = <-words
+			word = nextWord()
 		case ACTION_ACCEPT:
 			if word.Category == tokens.ItemEOF {
+				storage.InitializeGlobals()
+				frameSize = storage.FrameSize()
 				start, _ := storage.DestroyFunctionScope(runtime) //Destroy the final (outermost) scope
-				return start, nil                                 // success
+				return start, frameSize, nil                      // success
 			} else {
-				return 0, errors.New("syntax error")
+				return 0, 0, errors.New("syntax error")
 			}
 		default:
-			return 0, errors.New(fmt.Sprintln("invalid action state on", word))
+			return 0, 0, errors.New(fmt.Sprintln("invalid action state on", word))
 		}
 	}
 }
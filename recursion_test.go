@@ -0,0 +1,111 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRecursion(t *testing.T) {
+	expectOutput(t, `
+func fib(int n) int {
+  if n < 2 {
+    return n;
+  }
+  return fib(n - 1) + fib(n - 2);
+}
+echo(fib(15));
+func fact(int n) int {
+  if n < 2 {
+    return 1;
+  }
+  int r = n * fact(n - 1);
+  return r;
+}
+echo(fact(10));`, 610, 3628800)
+}
+
+func TestRecursionKeepsLocals(t *testing.T) {
+	expectOutput(t, `
+func fib(int n) int {
+  if n < 2 {
+    return n;
+  }
+  return fib(n - 1) + fib(n - 2);
+}
+func sum(int n) int {
+  int acc = 0;
+  for int i = 0; i < n; i = i + 1 {
+    int sq = i * i;
+    acc = acc + sq + fib(3);
+  }
+  return acc;
+}
+echo(sum(5));`, 40)
+}
+
+func TestDeepRecursion(t *testing.T) {
+	expectOutput(t, `
+func deep(int n) int {
+  if n == 0 {
+    return 0;
+  }
+  return 1 + deep(n - 1);
+}
+echo(deep(3000));`, 3000)
+}
+
+func TestStackOverflow(t *testing.T) {
+	expectError(t, `
+func forever(int n) int {
+  return 1 + forever(n + 1);
+}
+echo(forever(0));`, "stack overflow, exceeded the maximum call depth of 50", maxCallDepthEnv+"=50")
+}
+
+// Calls in tail position reuse the frame of the caller, so they are not limited by the call depth.
+func TestTailCalls(t *testing.T) {
+	res := runProgram(t, `
+func wait_for_floor(int floor, int target) int {
+  if floor == target {
+    return floor;
+  }
+  return wait_for_floor(floor + 1, target);
+}
+echo(wait_for_floor(0, 1000));
+func count(int n, int acc) int {
+  if n == 0 {
+    return acc;
+  } else {
+    return count(n - 1, acc + n);
+  }
+}
+echo(count(1000, 0));
+func make_loop(int limit) func (int) int {
+  func loop(int i) int {
+    if i >= limit {
+      return i;
+    }
+    return loop(i + 1);
+  }
+  return loop;
+}
+func (int) int l = make_loop(1000);
+echo(l(0));`, maxCallDepthEnv+"=50")
+	if res.err != "" {
+		t.Fatalf("unexpected error: %s", res.err)
+	}
+	if expected := []string{"1000", "500500", "1000"}; !slices.Equal(res.output, expected) {
+		t.Fatalf("expected output %q, got %q", expected, res.output)
+	}
+}
+
+func TestCallInExpressionIsNotTailCall(t *testing.T) {
+	expectError(t, `
+func deep(int n) int {
+  if n == 0 {
+    return 0;
+  }
+  return 1 + deep(n - 1);
+}
+echo(deep(1000));`, "stack overflow", maxCallDepthEnv+"=50")
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"dsl/variables"
+)
+
+// Create a new array from the values of Elements. Fixed size arrays are padded with Zero up to Length.
+type InstrMakeArray struct {
+	Elements []variables.Symbol
+	Length   int
+	Zero     any
+	Result   variables.Symbol
+}
+
+func (instr *InstrMakeArray) Execute(runtime *RuntimeInstance) {
+	elements := make([]any, max(len(instr.Elements), instr.Length))
+	for i := range elements {
+		if i < len(instr.Elements) {
+			elements[i] = variables.CopyValue(runtime.Get(instr.Elements[i]))
+		} else {
+			elements[i] = variables.CopyValue(instr.Zero)
+		}
+	}
+	runtime.Set(instr.Result, &variables.ArrayVar{Elements: elements})
+}
+
+type InstrIndexGet struct {
+	Array  variables.Symbol
+	Index  variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrIndexGet) Execute(runtime *RuntimeInstance) {
+	array := runtime.GetArray(instr.Array)
+	index := runtime.GetInt(instr.Index)
+	runtime.checkBounds(array, index)
+	runtime.Set(instr.Result, array.Elements[index])
+}
+
+type InstrIndexSet struct {
+	Array variables.Symbol
+	Index variables.Symbol
+	Value variables.Symbol
+}
+
+func (instr *InstrIndexSet) Execute(runtime *RuntimeInstance) {
+	array := runtime.GetArray(instr.Array)
+	index := runtime.GetInt(instr.Index)
+	runtime.checkBounds(array, index)
+	array.Elements[index] = variables.CopyValue(runtime.Get(instr.Value))
+}
+
+// Length of an array, a map or a string.
+type InstrLen struct {
+	Source variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrLen) Execute(runtime *RuntimeInstance) {
+	switch value := runtime.Get(instr.Source).(type) {
+	case *variables.ArrayVar:
+		runtime.Set(instr.Result, len(value.Elements))
+	case *variables.MapVar:
+		runtime.Set(instr.Result, len(value.Entries))
+	case string:
+		runtime.Set(instr.Result, len(value))
+	}
+}
+
+// Create a copy of Array with Values added to the end. When Result is Array itself, the values are added in place.
+type InstrAppend struct {
+	Array  variables.Symbol
+	Values []variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrAppend) Execute(runtime *RuntimeInstance) {
+	array := runtime.GetArray(instr.Array)
+	if !instr.Result.SameVariable(instr.Array) {
+		array = variables.CopyValue(array).(*variables.ArrayVar)
+	}
+	for i := range instr.Values {
+		array.Elements = append(array.Elements, variables.CopyValue(runtime.Get(instr.Values[i])))
+	}
+	runtime.Set(instr.Result, array)
+}
+
+func (runtime *RuntimeInstance) checkBounds(array *variables.ArrayVar, index int) {
+	if index < 0 || index >= len(array.Elements) {
+		runtime.Fatalf("index %d out of range for array of length %d", index, len(array.Elements))
+	}
+}
@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"dsl/variables"
+	"fmt"
+)
+
+// The operands read by an instruction that may be evaluated at compile time.
+// Returns false for any other instruction, e.g. function calls or instructions on heap values.
+func constantOperands(instruction Instruction) ([]variables.Symbol, bool) {
+	switch instr := instruction.(type) {
+	case *InstrLoadImmediate, *InstrNOP:
+		return nil, true
+	case *InstrArithmetic:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrArithmeticFloat:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrConcat:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrCompareInt:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrCompareFloat:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrCompareBool:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrCompareString:
+		return []variables.Symbol{instr.A, instr.B}, true
+	case *InstrNot:
+		return []variables.Symbol{instr.A}, true
+	case *InstrNegate:
+		return []variables.Symbol{instr.A}, true
+	case *InstrNegateFloat:
+		return []variables.Symbol{instr.A}, true
+	case *InstrAssign:
+		return []variables.Symbol{instr.Source}, true
+	case *InstrJmpIf:
+		return []variables.Symbol{instr.Condition}, true
+	case *InstrJmp:
+		return nil, true
+	}
+	return nil, false
+}
+
+// The label an instruction may jump to, or "" if it does not jump.
+func jumpTarget(instruction Instruction) string {
+	switch instr := instruction.(type) {
+	case *InstrJmp:
+		return instr.Label
+	case *InstrJmpIf:
+		return instr.Label
+	}
+	return ""
+}
+
+// Evaluate an expression at compile time, by running its instructions in a scratch runtime.
+// The instructions were compiled in a scope of frameSize variables, and may only read the temporaries
+// they create themselves, which are placed from the offset first and onwards.
+// Jumps must stay within the instructions, e.g. those of a conditional expression.
+func Evaluate(instructions []InstructionLabelPair, frameSize int, first int, result variables.Symbol) (value any, err error) {
+	labels := map[string]bool{}
+	for _, pair := range instructions {
+		if pair.Label != "" {
+			labels[pair.Label] = true
+		}
+	}
+	for _, pair := range instructions {
+		operands, ok := constantOperands(pair.Instruction)
+		if !ok {
+			return nil, fmt.Errorf("expression is not constant")
+		}
+		if label := jumpTarget(pair.Instruction); label != "" && !labels[label] {
+			return nil, fmt.Errorf("expression is not constant")
+		}
+		for _, operand := range operands {
+			if operand.Scope != 0 || operand.Captured || operand.Offset < first {
+				return nil, fmt.Errorf("expression is not constant")
+			}
+		}
+	}
+	if result.Scope != 0 || result.Captured || result.Offset < first {
+		return nil, fmt.Errorf("expression is not constant")
+	}
+
+	// Errors such as an integer division by zero panic in the scratch runtime
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("invalid constant expression: %v", r)
+		}
+	}()
+
+	scratch := New()
+	scratch.LoadInstructions(instructions)
+	instance := scratch.NewInstance(0, frameSize)
+	instance.Run()
+	return instance.Get(result), nil
+}
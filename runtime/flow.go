@@ -0,0 +1,45 @@
+package runtime
+
+// Whether running instructions from the first one can reach past the last one, rather than
+// always leaving through a return or a tail call. Jumps are followed both ways, regardless of their condition.
+func ReachesEnd(instructions []InstructionLabelPair) bool {
+	labels := map[string]int{}
+	for i, pair := range instructions {
+		if pair.Label != "" {
+			labels[pair.Label] = i
+		}
+	}
+
+	visited := make([]bool, len(instructions))
+	pending := []int{0}
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if i >= len(instructions) {
+			return true
+		}
+		if visited[i] {
+			continue
+		}
+		visited[i] = true
+
+		switch instr := instructions[i].Instruction.(type) {
+		case *InstrExitFunction, *InstrTailCall:
+		case *InstrJmp:
+			target, ok := labels[instr.Label]
+			if !ok {
+				return true
+			}
+			pending = append(pending, target)
+		case *InstrJmpIf:
+			target, ok := labels[instr.Label]
+			if !ok {
+				return true
+			}
+			pending = append(pending, target, i+1)
+		default:
+			pending = append(pending, i+1)
+		}
+	}
+	return false
+}
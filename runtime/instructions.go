@@ -2,10 +2,10 @@ package runtime
 
 import (
 	"dsl/color"
-	"dsl/structure"
 	"dsl/variables"
 	"fmt"
 	"slices"
+	"strings"
 )
 
 const (
@@ -42,12 +42,15 @@ const (
 func (op BooleanOperator) IsValidFor(t variables.Type) bool {
 	legalBools := []BooleanOperator{EQUALS, NOTEQUALS, AND, OR, NOT}
 	legalInts := []BooleanOperator{EQUALS, NOTEQUALS, LESS, LESSOREQUAL, GREATER, GREATEROREQUAL}
+	legalStrings := []BooleanOperator{EQUALS, NOTEQUALS}
 
 	switch t {
 	case variables.BOOL:
 		return slices.Contains(legalBools, op)
-	case variables.INT:
+	case variables.INT, variables.FLOAT:
 		return slices.Contains(legalInts, op)
+	case variables.STRING:
+		return slices.Contains(legalStrings, op)
 	}
 
 	return false
@@ -80,6 +83,26 @@ func (instr *InstrArithmetic) Execute(runtime *RuntimeInstance) {
 	}
 }
 
+type InstrArithmeticFloat struct {
+	A        variables.Symbol
+	B        variables.Symbol
+	Operator Operator
+	Result   variables.Symbol
+}
+
+func (instr *InstrArithmeticFloat) Execute(runtime *RuntimeInstance) {
+	switch instr.Operator {
+	case ADD:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A)+runtime.GetFloat(instr.B))
+	case MULT:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A)*runtime.GetFloat(instr.B))
+	case DIV:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A)/runtime.GetFloat(instr.B))
+	case SUB:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A)-runtime.GetFloat(instr.B))
+	}
+}
+
 type InstrCompareInt struct {
 	A        variables.Symbol
 	B        variables.Symbol
@@ -105,6 +128,30 @@ func (instr *InstrCompareInt) Execute(runtime *RuntimeInstance) {
 	}
 }
 
+type InstrCompareFloat struct {
+	A        variables.Symbol
+	B        variables.Symbol
+	Operator BooleanOperator
+	Result   variables.Symbol
+}
+
+func (instr *InstrCompareFloat) Execute(runtime *RuntimeInstance) {
+	switch instr.Operator {
+	case EQUALS:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A) == runtime.GetFloat(instr.B))
+	case NOTEQUALS:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A) != runtime.GetFloat(instr.B))
+	case LESS:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A) < runtime.GetFloat(instr.B))
+	case LESSOREQUAL:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A) <= runtime.GetFloat(instr.B))
+	case GREATER:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A) > runtime.GetFloat(instr.B))
+	case GREATEROREQUAL:
+		runtime.Set(instr.Result, runtime.GetFloat(instr.A) >= runtime.GetFloat(instr.B))
+	}
+}
+
 type InstrCompareBool struct {
 	A        variables.Symbol
 	B        variables.Symbol
@@ -125,6 +172,80 @@ func (instr *InstrCompareBool) Execute(runtime *RuntimeInstance) {
 	}
 }
 
+type InstrNot struct {
+	A      variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrNot) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Result, !runtime.GetBool(instr.A))
+}
+
+type InstrNegate struct {
+	A      variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrNegate) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Result, -runtime.GetInt(instr.A))
+}
+
+type InstrNegateFloat struct {
+	A      variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrNegateFloat) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Result, -runtime.GetFloat(instr.A))
+}
+
+type InstrCompareString struct {
+	A        variables.Symbol
+	B        variables.Symbol
+	Operator BooleanOperator
+	Result   variables.Symbol
+}
+
+func (instr *InstrCompareString) Execute(runtime *RuntimeInstance) {
+	switch instr.Operator {
+	case EQUALS:
+		runtime.Set(instr.Result, runtime.GetString(instr.A) == runtime.GetString(instr.B))
+	case NOTEQUALS:
+		runtime.Set(instr.Result, runtime.GetString(instr.A) != runtime.GetString(instr.B))
+	}
+}
+
+// Concatenate the strings A and B.
+type InstrConcat struct {
+	A      variables.Symbol
+	B      variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrConcat) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Result, runtime.GetString(instr.A)+runtime.GetString(instr.B))
+}
+
+// Convert an int to a float.
+type InstrIntToFloat struct {
+	Source variables.Symbol
+	Dest   variables.Symbol
+}
+
+func (instr *InstrIntToFloat) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Dest, float64(runtime.GetInt(instr.Source)))
+}
+
+// Convert a float to an int, truncating towards zero.
+type InstrFloatToInt struct {
+	Source variables.Symbol
+	Dest   variables.Symbol
+}
+
+func (instr *InstrFloatToInt) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Dest, int(runtime.GetFloat(instr.Source)))
+}
+
 type InstrJmp struct {
 	Label string
 }
@@ -162,25 +283,26 @@ func (instr *InstrLoadImmediate) Execute(runtime *RuntimeInstance) {
 }
 
 type InstrLoadFunction struct {
-	Symbol variables.Symbol
-	Label  string
+	Symbol    variables.Symbol
+	Label     string
+	Captures  []variables.Symbol // Variables used by the function, but declared outside of it. Set when the function body is compiled.
+	FrameSize int                // Set when the function body is compiled.
 }
 
 func (instr *InstrLoadFunction) Execute(runtime *RuntimeInstance) {
-	// Copy the current address stack.
-	var address_stack structure.Stack[int]
-	src_address_stack := runtime.CallStack.Peek().AddressStack
-	for i := range src_address_stack {
-		address_stack.Push(src_address_stack[i])
+	env := make([]*variables.Cell, len(instr.Captures))
+	for i := range instr.Captures {
+		env[i] = runtime.Capture(instr.Captures[i])
 	}
 	runtime.Set(instr.Symbol, variables.FunctionVar{
-		Label:        instr.Label,
-		AddressStack: address_stack,
+		Label:     instr.Label,
+		Env:       env,
+		FrameSize: instr.FrameSize,
 	})
 }
 
 func (instr *InstrAssign) Execute(runtime *RuntimeInstance) {
-	runtime.Set(instr.Dest, runtime.Get(instr.Source))
+	runtime.Set(instr.Dest, variables.CopyValue(runtime.Get(instr.Source)))
 }
 
 type InstructionEcho struct {
@@ -188,7 +310,21 @@ type InstructionEcho struct {
 }
 
 func (instr *InstructionEcho) Execute(runtime *RuntimeInstance) {
-	color.Println(color.Green, runtime.Runtime.Variables[runtime.AddressFromSymbol(instr.A)])
+	color.Println(color.Green, runtime.Get(instr.A))
+}
+
+// Print all values on one line, separated by spaces.
+type InstrPrint struct {
+	Values variables.Symbol
+}
+
+func (instr *InstrPrint) Execute(runtime *RuntimeInstance) {
+	values := runtime.GetArray(instr.Values).Elements
+	formatted := make([]string, len(values))
+	for i := range values {
+		formatted[i] = fmt.Sprint(values[i])
+	}
+	color.Println(color.Green, strings.Join(formatted, " "))
 }
 
 type InstrCallFunction struct {
@@ -202,7 +338,7 @@ func (instr *InstrCallFunction) Execute(runtime *RuntimeInstance) {
 	//Fetch and copy argument values
 	var arg_values []any
 	for i := range instr.Arguments {
-		arg_values = append(arg_values, runtime.Get(instr.Arguments[i]))
+		arg_values = append(arg_values, variables.CopyValue(runtime.Get(instr.Arguments[i])))
 	}
 	//Fetch func_ptr
 	func_ptr := runtime.Get(instr.SymbolicLabel).(variables.FunctionVar)
@@ -213,7 +349,7 @@ func (instr *InstrCallFunction) Execute(runtime *RuntimeInstance) {
 	fmt.Println("Bound ret val to", top_ar.Retval)
 
 	// Account for prelude length
-	runtime.PushCall(instr.PreludeLength, func_ptr.AddressStack)
+	runtime.PushCall(instr.PreludeLength, func_ptr)
 
 	// Once "inside" the function, load argument values
 	for i := range arg_values {
@@ -227,30 +363,68 @@ func (instr *InstrCallFunction) Execute(runtime *RuntimeInstance) {
 	jmp_instr.Execute(runtime)
 }
 
-type InstrBeginScope struct{}
+// A call in tail position. The current activation register is reused for the callee,
+// so the callee returns directly to the caller of the current function.
+type InstrTailCall struct {
+	Arguments     []variables.Symbol
+	SymbolicLabel variables.Symbol
+}
+
+func (instr *InstrTailCall) Execute(runtime *RuntimeInstance) {
+	var arg_values []any
+	for i := range instr.Arguments {
+		arg_values = append(arg_values, variables.CopyValue(runtime.Get(instr.Arguments[i])))
+	}
+	func_ptr := runtime.Get(instr.SymbolicLabel).(variables.FunctionVar)
+
+	runtime.ReuseCall(func_ptr)
+
+	for i := range arg_values {
+		runtime.Set(variables.Symbol{Offset: i, Scope: 0, Type: instr.Arguments[i].Type}, arg_values[i])
+	}
+
+	jmp_instr := InstrJmpVar{
+		Label: func_ptr.Label,
+	}
+	jmp_instr.Execute(runtime)
+}
+
+type InstrBeginScope struct {
+	Size int // Number of variables declared in the scope. Set when the scope is compiled.
+}
 
 func (instr *InstrBeginScope) Execute(runtime *RuntimeInstance) {
-	runtime.CallStack.PeekRef().PushAddress()
+	runtime.PushScope(instr.Size)
 }
 
 type InstrEndScope struct{}
 
 func (instr *InstrEndScope) Execute(runtime *RuntimeInstance) {
-	runtime.CallStack.PeekRef().PopAddress()
+	runtime.ClearVariables(runtime.CallStack.PeekRef().PopAddress())
 }
 
 type InstrExitFunction struct {
-	RetVal variables.Symbol
+	RetVal variables.Symbol // Left unset when no value is returned, e.g. from a void function. A tuple for multiple values.
 }
 
 func (instr *InstrExitFunction) Execute(runtime *RuntimeInstance) {
-	src_val := runtime.Get(instr.RetVal)
+	if instr.RetVal.Type.BaseType == variables.INVALID {
+		runtime.PopCall()
+		return
+	}
+
+	var src_vals []any
+	for _, value := range instr.RetVal.Values() {
+		src_vals = append(src_vals, runtime.Get(value))
+	}
 
 	runtime.PopCall()
 	top_ar := runtime.CallStack.PeekRef()
 
-	fmt.Println("Ret val on exit", top_ar.Retval, src_val)
-	runtime.Set(top_ar.Retval, src_val)
+	fmt.Println("Ret val on exit", top_ar.Retval, src_vals)
+	for i, dest := range top_ar.Retval.Values() {
+		runtime.Set(dest, src_vals[i])
+	}
 }
 
 // Does nothing.
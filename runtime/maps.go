@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"dsl/variables"
+)
+
+// Create a new map from pairs of Keys and Values. Later pairs override earlier ones with the same key.
+type InstrMakeMap struct {
+	Keys   []variables.Symbol
+	Values []variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrMakeMap) Execute(runtime *RuntimeInstance) {
+	entries := make(map[any]any, len(instr.Keys))
+	for i := range instr.Keys {
+		entries[runtime.Get(instr.Keys[i])] = variables.CopyValue(runtime.Get(instr.Values[i]))
+	}
+	runtime.Set(instr.Result, &variables.MapVar{Entries: entries})
+}
+
+// Look up Key in Map. If the key is not present, the result is Zero.
+type InstrMapGet struct {
+	Map    variables.Symbol
+	Key    variables.Symbol
+	Zero   any
+	Result variables.Symbol
+	Insert bool // Set if the result is written through, e.g. "m[key].field = 1;". A missing key is then added with the result.
+}
+
+func (instr *InstrMapGet) Execute(runtime *RuntimeInstance) {
+	entries := runtime.GetMap(instr.Map).Entries
+	key := runtime.Get(instr.Key)
+	value, ok := entries[key]
+	if !ok {
+		value = variables.CopyValue(instr.Zero)
+		if instr.Insert {
+			entries[key] = value
+		}
+	}
+	runtime.Set(instr.Result, value)
+}
+
+type InstrMapSet struct {
+	Map   variables.Symbol
+	Key   variables.Symbol
+	Value variables.Symbol
+}
+
+func (instr *InstrMapSet) Execute(runtime *RuntimeInstance) {
+	runtime.GetMap(instr.Map).Entries[runtime.Get(instr.Key)] = variables.CopyValue(runtime.Get(instr.Value))
+}
+
+type InstrMapDelete struct {
+	Map variables.Symbol
+	Key variables.Symbol
+}
+
+func (instr *InstrMapDelete) Execute(runtime *RuntimeInstance) {
+	delete(runtime.GetMap(instr.Map).Entries, runtime.Get(instr.Key))
+}
+
+type InstrMapHas struct {
+	Map    variables.Symbol
+	Key    variables.Symbol
+	Result variables.Symbol
+}
+
+func (instr *InstrMapHas) Execute(runtime *RuntimeInstance) {
+	_, ok := runtime.GetMap(instr.Map).Entries[runtime.Get(instr.Key)]
+	runtime.Set(instr.Result, ok)
+}
+
+// Copy the keys of Map, in sorted order, and their values into the arrays Keys and Values.
+type InstrMapEntries struct {
+	Map    variables.Symbol
+	Keys   variables.Symbol
+	Values variables.Symbol
+}
+
+func (instr *InstrMapEntries) Execute(runtime *RuntimeInstance) {
+	m := runtime.GetMap(instr.Map)
+	keys := m.SortedKeys()
+	values := make([]any, len(keys))
+	for i := range keys {
+		values[i] = variables.CopyValue(m.Entries[keys[i]])
+	}
+	runtime.Set(instr.Keys, &variables.ArrayVar{Elements: keys})
+	runtime.Set(instr.Values, &variables.ArrayVar{Elements: values})
+}
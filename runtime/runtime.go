@@ -13,8 +13,11 @@ type Runtime struct {
 	Instructions []Instruction
 	Labels       map[string]int
 	Variables    []any
+	MaxCallDepth int
 }
 
+const DefaultMaxCallDepth = 10000
+
 type RuntimeInstance struct {
 	Runtime        *Runtime
 	Programcounter int
@@ -28,21 +31,25 @@ type ActivationRegister struct {
 	AddressStack structure.Stack[int]
 	AddressBegin int
 	StackTop     int
+	Closure      []*variables.Cell // Environment of the running function, see variables.FunctionVar
 }
 
 func New() *Runtime {
 	runTime := Runtime{
-		Variables: make([]any, 1000),
-		Labels:    map[string]int{},
+		Variables:    make([]any, 1000),
+		Labels:       map[string]int{},
+		MaxCallDepth: DefaultMaxCallDepth,
 	}
 
 	return &runTime
 }
 
-func (runtime *Runtime) NewInstance(entryPoint int) RuntimeInstance {
+// Create an instance starting at entryPoint, with frameSize variables reserved for the outermost scope.
+func (runtime *Runtime) NewInstance(entryPoint int, frameSize int) RuntimeInstance {
 	first_ar := ActivationRegister{
 		SavedPC:      0,
 		AddressBegin: 0,
+		StackTop:     frameSize - 1,
 	}
 	first_ar.AddressStack.Push(0)
 	instance := RuntimeInstance{
@@ -50,6 +57,7 @@ func (runtime *Runtime) NewInstance(entryPoint int) RuntimeInstance {
 		Runtime:        runtime,
 	}
 	instance.CallStack.Push(first_ar)
+	instance.reserve(first_ar.StackTop)
 	return instance
 }
 
@@ -61,40 +69,98 @@ func (runtime *Runtime) GetLabel(label string) int {
 	return value
 }
 
-func (ar *ActivationRegister) PushAddress() {
+// Push a scope of size variables, placed after the variables of the enclosing scopes.
+func (ar *ActivationRegister) PushAddress(size int) {
 	ar.AddressStack.Push(ar.StackTop + 1)
+	ar.StackTop += size
 	fmt.Println("Adress stack pushed at ", ar)
 }
 
-func (ar *ActivationRegister) PopAddress() {
-	ar.StackTop = ar.AddressStack.Pop()
+// Pop the innermost scope, returning the range of addresses it used.
+func (ar *ActivationRegister) PopAddress() (int, int) {
+	start, end := ar.AddressStack.Pop(), ar.StackTop
+	ar.StackTop = start - 1
+	return start, end
 }
 
-func (runtime *RuntimeInstance) PushCall(offset int, func_address_stack structure.Stack[int]) {
-	// The address stack in the function must have an address stack equal to how it looked
-	// when the function was defined.
+func (runtime *RuntimeInstance) PushScope(size int) {
+	ar := runtime.CallStack.PeekRef()
+	ar.PushAddress(size)
+	runtime.reserve(ar.StackTop)
+}
+
+func (runtime *RuntimeInstance) PushCall(offset int, function variables.FunctionVar) {
+	if len(runtime.CallStack) > runtime.Runtime.MaxCallDepth {
+		runtime.Fatalf("stack overflow, exceeded the maximum call depth of %d", runtime.Runtime.MaxCallDepth)
+	}
+
+	// Variables from outside the function are reached through its closure, so
+	// the function's address stack begins at the next available address.
 	top_of_callstack := runtime.CallStack.Peek()
 	var addr_stack structure.Stack[int]
-	for i := range func_address_stack {
-		addr_stack.Push(func_address_stack[i])
-	}
-	// The beginning of the next address stack then begins at the next avaiable address
 	addr_stack.Push(top_of_callstack.StackTop + 1)
 	runtime.CallStack.Push(ActivationRegister{
 		SavedPC:      runtime.Programcounter + offset,
 		AddressStack: addr_stack,
 		AddressBegin: top_of_callstack.StackTop + 1,
+		StackTop:     top_of_callstack.StackTop + function.FrameSize,
+		Closure:      function.Env,
 	})
+	runtime.reserve(runtime.CallStack.Peek().StackTop)
+
+	fmt.Println("PushCall with AR = ", runtime.CallStack.Peek())
+}
+
+// Replace the frame of the running function with a frame for function, keeping the saved PC.
+func (runtime *RuntimeInstance) ReuseCall(function variables.FunctionVar) {
+	ar := runtime.CallStack.PeekRef()
+	runtime.ClearVariables(ar.AddressBegin, ar.StackTop)
 
-	fmt.Println("PushCall with AR = ", runtime.CallStack.Peek(), func_address_stack)
+	var addr_stack structure.Stack[int]
+	addr_stack.Push(ar.AddressBegin)
+	ar.AddressStack = addr_stack
+	ar.StackTop = ar.AddressBegin + function.FrameSize - 1
+	ar.Closure = function.Env
+	runtime.reserve(ar.StackTop)
+}
+
+// Grow the memory until addr is valid.
+func (runtime *RuntimeInstance) reserve(addr int) {
+	for addr >= len(runtime.Runtime.Variables) {
+		runtime.Runtime.Variables = append(runtime.Runtime.Variables, make([]any, len(runtime.Runtime.Variables))...)
+	}
 }
 
 func (runtime *RuntimeInstance) PopCall() {
 	val := runtime.CallStack.Pop()
+	runtime.ClearVariables(val.AddressBegin, val.StackTop)
 	runtime.Programcounter = val.SavedPC - 1
 	fmt.Println("PopCall, AR = ", runtime.CallStack.Peek())
 }
 
+// Release the variables in the address range [start, end] when their scope ends.
+// Captured variables hold a cell that must not be written through once the address is reused.
+func (runtime *RuntimeInstance) ClearVariables(start int, end int) {
+	for addr := start; addr <= end; addr++ {
+		runtime.Runtime.Variables[addr] = nil
+	}
+}
+
+// Get the cell holding the variable of symbol, moving the variable into a new cell if it is not captured already.
+func (runtime *RuntimeInstance) Capture(symbol variables.Symbol) *variables.Cell {
+	if symbol.Captured {
+		return runtime.CallStack.Peek().Closure[symbol.Offset]
+	}
+
+	addr := runtime.AddressFromSymbol(symbol)
+	cell, ok := runtime.Runtime.Variables[addr].(*variables.Cell)
+	if !ok {
+		cell = &variables.Cell{Value: runtime.Runtime.Variables[addr]}
+		runtime.Runtime.Variables[addr] = cell
+	}
+	return cell
+}
+
 // Add the set of instructions. Return the first and last index of the inserted instructions.
 func (runtime *Runtime) LoadInstructions(instructions []InstructionLabelPair) (start int, end int) {
 	for _, pair := range instructions {
@@ -120,6 +186,11 @@ func (runtime *RuntimeInstance) Run() {
 	}
 }
 
+// Stop the program with an error that occurred while running it.
+func (r *RuntimeInstance) Fatalf(format string, args ...any) {
+	log.Fatalf("runtime error at instruction %d: %s", r.Programcounter, fmt.Sprintf(format, args...))
+}
+
 func (r *RuntimeInstance) AddressFromSymbol(symbol variables.Symbol) int {
 	top_of_callstack := r.CallStack.PeekRef()
 
@@ -129,8 +200,15 @@ func (r *RuntimeInstance) AddressFromSymbol(symbol variables.Symbol) int {
 }
 
 func (s *RuntimeInstance) Get(symbol variables.Symbol) any {
+	if symbol.Captured {
+		return s.CallStack.Peek().Closure[symbol.Offset].Value
+	}
+
 	addr := s.AddressFromSymbol(symbol)
 	resolve := s.Runtime.Variables[addr]
+	if cell, ok := resolve.(*variables.Cell); ok {
+		resolve = cell.Value
+	}
 
 	fmt.Println("Get", symbol, "val=", resolve, "addr=", addr)
 	return resolve
@@ -146,12 +224,37 @@ func (r *RuntimeInstance) GetBool(symbol variables.Symbol) bool {
 	return r.Get(symbol).(bool)
 }
 
+func (r *RuntimeInstance) GetArray(symbol variables.Symbol) *variables.ArrayVar {
+	return r.Get(symbol).(*variables.ArrayVar)
+}
+
+func (r *RuntimeInstance) GetStruct(symbol variables.Symbol) *variables.StructVar {
+	return r.Get(symbol).(*variables.StructVar)
+}
+
+func (r *RuntimeInstance) GetMap(symbol variables.Symbol) *variables.MapVar {
+	return r.Get(symbol).(*variables.MapVar)
+}
+
+func (r *RuntimeInstance) GetFloat(symbol variables.Symbol) float64 {
+	return r.Get(symbol).(float64)
+}
+
+func (r *RuntimeInstance) GetString(symbol variables.Symbol) string {
+	return r.Get(symbol).(string)
+}
+
 func (s *RuntimeInstance) Set(symbol variables.Symbol, value any) {
+	if symbol.Captured {
+		s.CallStack.Peek().Closure[symbol.Offset].Value = value
+		return
+	}
+
 	addr := s.AddressFromSymbol(symbol)
-	s.Runtime.Variables[addr] = value
-	stack_top := &s.CallStack.PeekRef().StackTop
-	if addr > *stack_top {
-		*stack_top = addr
+	if cell, ok := s.Runtime.Variables[addr].(*variables.Cell); ok {
+		cell.Value = value
+	} else {
+		s.Runtime.Variables[addr] = value
 	}
 	fmt.Println("Set", symbol, "value=", value, "addr=", addr)
 }
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"dsl/variables"
+)
+
+// Create a new struct from Zero, the zero value of the struct type, with the fields at Indices set to Values.
+type InstrMakeStruct struct {
+	Zero    *variables.StructVar
+	Indices []int
+	Values  []variables.Symbol
+	Result  variables.Symbol
+}
+
+func (instr *InstrMakeStruct) Execute(runtime *RuntimeInstance) {
+	value := variables.CopyValue(instr.Zero).(*variables.StructVar)
+	for i := range instr.Values {
+		value.Fields[instr.Indices[i]] = variables.CopyValue(runtime.Get(instr.Values[i]))
+	}
+	runtime.Set(instr.Result, value)
+}
+
+type InstrFieldGet struct {
+	Struct variables.Symbol
+	Index  int
+	Result variables.Symbol
+}
+
+func (instr *InstrFieldGet) Execute(runtime *RuntimeInstance) {
+	runtime.Set(instr.Result, runtime.GetStruct(instr.Struct).Fields[instr.Index])
+}
+
+type InstrFieldSet struct {
+	Struct variables.Symbol
+	Index  int
+	Value  variables.Symbol
+}
+
+func (instr *InstrFieldSet) Execute(runtime *RuntimeInstance) {
+	runtime.GetStruct(instr.Struct).Fields[instr.Index] = variables.CopyValue(runtime.Get(instr.Value))
+}
@@ -4,31 +4,47 @@ import (
 	"dsl/tokens"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 )
 
 type lexer struct {
-	name  string
-	input string
-	start int
-	pos   int
-	width int
-	items chan tokens.Token
+	name     string
+	input    string
+	start    int
+	pos      int
+	width    int
+	line     int // Line of pos, counting from 1
+	items    chan tokens.Token
+	comments []Comment
+	scanned  chan []Comment
+}
+
+// A comment in the input. Comments are not part of the token stream, they are kept for tools
+// such as formatters that need to reattach them to the surrounding code.
+type Comment struct {
+	Text string // The comment as written, including its delimiters
+	Pos  int    // Byte offset of the comment in the input
+	Line int
 }
 
 const eof rune = '\x00' // necessary in 2025?
 
 type stateFn func(*lexer) stateFn
 
-func Lex(name string, input string) (*lexer, chan tokens.Token) {
+// Scan input, sending its tokens on the returned token channel.
+// The comments of the input are sent on the comment channel once all of the input is scanned.
+func Lex(name string, input string) (*lexer, chan tokens.Token, chan []Comment) {
 	l := &lexer{
-		name:  name,
-		input: input,
-		items: make(chan tokens.Token),
+		name:    name,
+		input:   input,
+		line:    1,
+		items:   make(chan tokens.Token),
+		scanned: make(chan []Comment, 1),
 	}
 	go l.run()
-	return l, l.items
+	return l, l.items, l.scanned
 }
 
 func (l *lexer) run() {
@@ -36,6 +52,8 @@ func (l *lexer) run() {
 		state = state(l)
 	}
 	close(l.items)
+	l.scanned <- l.comments
+	close(l.scanned)
 }
 
 func (l *lexer) emit(t tokens.ItemType) {
@@ -43,6 +61,12 @@ func (l *lexer) emit(t tokens.ItemType) {
 	l.start = l.pos
 }
 
+// Emit a token with a lexeme that differs from the input text, e.g. a decoded string literal.
+func (l *lexer) emitLexeme(t tokens.ItemType, lexeme string) {
+	l.items <- tokens.Token{Category: t, Lexeme: lexeme}
+	l.start = l.pos
+}
+
 func (l *lexer) next() rune {
 	if l.pos >= len(l.input) {
 		l.width = 0
@@ -51,6 +75,9 @@ func (l *lexer) next() rune {
 	_rune, width := utf8.DecodeRuneInString(l.input[l.pos:])
 	l.width = width
 	l.pos += l.width
+	if _rune == '\n' {
+		l.line += 1
+	}
 	return _rune
 }
 
@@ -74,6 +101,9 @@ func (l *lexer) ignore() {
 
 func (l *lexer) backup() {
 	l.pos -= l.width
+	if l.width > 0 && l.input[l.pos] == '\n' {
+		l.line -= 1
+	}
 }
 
 func (l *lexer) peek() rune {
@@ -101,6 +131,7 @@ func (l *lexer) acceptRegex(valid string) {
 
 	ret := re.FindStringIndex(l.input[l.pos:])
 	if ret != nil {
+		l.line += strings.Count(l.input[l.pos:l.pos+ret[1]], "\n")
 		l.pos += ret[1]
 	}
 }
@@ -113,6 +144,33 @@ func (l *lexer) errorf(format string, args ...interface{}) stateFn {
 	return nil
 }
 
+// Scan a comment, the leading slash is already consumed and the next rune is / or *.
+// Returns false if a block comment is not terminated.
+func (l *lexer) comment() bool {
+	line := l.line
+	if l.next() == '/' {
+		for r := l.next(); r != '\n' && r != eof; r = l.next() {
+		}
+		if l.width > 0 {
+			l.backup()
+		}
+	} else {
+		for !strings.HasSuffix(l.input[l.start+2:l.pos], "*/") {
+			if l.next() == eof {
+				return false
+			}
+		}
+	}
+
+	l.comments = append(l.comments, Comment{
+		Text: l.input[l.start:l.pos],
+		Pos:  l.start,
+		Line: line,
+	})
+	l.ignore()
+	return true
+}
+
 func isAlphaNumeric(c rune) bool {
 	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
 }
@@ -147,19 +205,54 @@ func lexInsideExpression(l *lexer) stateFn {
 		} else if r == '"' {
 			return lexQuote
 		} else if r == '+' {
-			l.emit(tokens.ItemOpPlus)
+			if l.peek() == '=' {
+				l.next()
+				l.emit(tokens.ItemPlusEquals)
+			} else if l.peek() == '+' {
+				l.next()
+				l.emit(tokens.ItemIncrement)
+			} else {
+				l.emit(tokens.ItemOpPlus)
+			}
 			return lexInsideExpression
 		} else if r == '-' {
-			l.emit(tokens.ItemOpMinus)
+			if l.peek() == '=' {
+				l.next()
+				l.emit(tokens.ItemMinusEquals)
+			} else if l.peek() == '-' {
+				l.next()
+				l.emit(tokens.ItemDecrement)
+			} else {
+				l.emit(tokens.ItemOpMinus)
+			}
 			return lexInsideExpression
 		} else if r == '*' {
-			l.emit(tokens.ItemOpMult)
+			if l.peek() == '=' {
+				l.next()
+				l.emit(tokens.ItemMultEquals)
+			} else {
+				l.emit(tokens.ItemOpMult)
+			}
 			return lexInsideExpression
+		} else if r == '/' && (l.peek() == '/' || l.peek() == '*') {
+			if !l.comment() {
+				return l.errorf("Unterminated block comment")
+			}
 		} else if r == '/' {
-			l.emit(tokens.ItemOpDiv)
+			if l.peek() == '=' {
+				l.next()
+				l.emit(tokens.ItemDivEquals)
+			} else {
+				l.emit(tokens.ItemOpDiv)
+			}
 			return lexInsideExpression
 		} else if r == '%' {
-			l.emit(tokens.ItemOpMod)
+			if l.peek() == '=' {
+				l.next()
+				l.emit(tokens.ItemModEquals)
+			} else {
+				l.emit(tokens.ItemOpMod)
+			}
 			return lexInsideExpression
 		} else if r == '(' {
 			l.emit(tokens.ItemParOpen)
@@ -172,16 +265,43 @@ func lexInsideExpression(l *lexer) stateFn {
 			return lexInsideExpression
 		} else if r == '}' {
 			l.emit(tokens.ItemScopeClose)
-			return lexInsideExpression
+			return lexInsideScope
 		} else if r == ',' {
 			l.emit(tokens.ItemComma)
 			return lexInsideExpression
+		} else if r == '.' {
+			if l.accept(".") {
+				if !l.accept(".") {
+					return l.errorf("expected ..., got ..")
+				}
+				l.emit(tokens.ItemEllipsis)
+			} else {
+				l.emit(tokens.ItemDot)
+			}
+			return lexInsideExpression
+		} else if r == ':' {
+			if l.peek() == '=' {
+				l.next()
+				l.emit(tokens.ItemDeclare)
+			} else {
+				l.emit(tokens.ItemColon)
+			}
+			return lexInsideExpression
+		} else if r == '[' {
+			l.emit(tokens.ItemBracketOpen)
+			return lexInsideExpression
+		} else if r == ']' {
+			l.emit(tokens.ItemBracketClose)
+			return lexInsideExpression
 		} else if r == '&' {
 			l.emit(tokens.ItemBoolAnd)
 			return lexInsideExpression
 		} else if r == '|' {
 			l.emit(tokens.ItemBoolOr)
 			return lexInsideExpression
+		} else if r == '?' {
+			l.emit(tokens.ItemQuestion)
+			return lexInsideExpression
 		} else if r == '<' {
 			if l.peek() == '=' {
 				l.next()
@@ -228,6 +348,10 @@ func lexInsideScope(l *lexer) stateFn {
 		} else if r == eof {
 			l.emit(tokens.ItemEOF)
 			return nil
+		} else if r == '/' && (l.peek() == '/' || l.peek() == '*') {
+			if !l.comment() {
+				return l.errorf("Unterminated block comment")
+			}
 		} else {
 			l.backup()
 			return lexInsideExpression
@@ -238,16 +362,69 @@ func lexInsideScope(l *lexer) stateFn {
 func lexNumber(l *lexer) stateFn {
 	//Optional leading sign
 	l.accept("+-")
-	l.acceptRun("0123456789")
+
+	// A prefix selects the base of an integer, e.g. 0xFF, 0b1010 or 0o755
+	digits := "0123456789"
+	prefixed := l.accept("0") && l.accept("xXbBoO")
+	if prefixed {
+		switch l.input[l.pos-1] {
+		case 'x', 'X':
+			digits = "0123456789abcdefABCDEF"
+		case 'b', 'B':
+			digits = "01"
+		default:
+			digits = "01234567"
+		}
+	}
+	l.acceptRun(digits + "_")
+
+	// A fractional part or exponent makes it a floating point number, e.g. 3.0, 2.5e-3 or 1e3
+	token := tokens.ItemNumber
+	if !prefixed {
+		if l.accept(".") {
+			token = tokens.ItemFloat
+			l.acceptRun("0123456789_")
+		}
+		if l.accept("eE") {
+			token = tokens.ItemFloat
+			l.accept("+-")
+			if !l.accept("0123456789") {
+				return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
+			}
+			l.acceptRun("0123456789_")
+		}
+	}
 
 	if isAlphaNumeric(l.peek()) {
 		l.next()
 		return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
 	}
-	l.emit(tokens.ItemNumber)
+	if prefixed && !strings.ContainsAny(l.input[l.start+2:l.pos], digits) {
+		return l.errorf("bad number syntax: %q", l.input[l.start:l.pos])
+	}
+	if !validSeparators(l.input[l.start:l.pos], digits) {
+		return l.errorf("'_' must separate successive digits: %q", l.input[l.start:l.pos])
+	}
+	l.emit(token)
 	return lexInsideExpression
 }
 
+// Digits may be separated by single underscores, e.g. 1_000_000 or 0b_1010_0101.
+func validSeparators(number string, digits string) bool {
+	for i := range number {
+		if number[i] != '_' {
+			continue
+		}
+		if i == 0 || !strings.ContainsRune(digits+"xXbBoO", rune(number[i-1])) {
+			return false
+		}
+		if i == len(number)-1 || !strings.ContainsRune(digits, rune(number[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
 func lexIdentifier(l *lexer) stateFn {
 	//We know the first is alphanumeric
 	l.acceptRegex("0-9a-zA-Z_")
@@ -257,6 +434,10 @@ func lexIdentifier(l *lexer) stateFn {
 		l.emit(tokens.ItemKeyInt)
 	} else if current == "bool" {
 		l.emit(tokens.ItemKeyBool)
+	} else if current == "string" {
+		l.emit(tokens.ItemKeyString)
+	} else if current == "float" {
+		l.emit(tokens.ItemKeyFloat)
 	} else if current == "false" {
 		l.emit(tokens.ItemFalse)
 	} else if current == "true" {
@@ -269,6 +450,40 @@ func lexIdentifier(l *lexer) stateFn {
 		l.emit(tokens.ItemReturn)
 	} else if current == "else" {
 		l.emit(tokens.ItemElse)
+	} else if current == "while" {
+		l.emit(tokens.ItemWhile)
+	} else if current == "for" {
+		l.emit(tokens.ItemFor)
+	} else if current == "break" {
+		l.emit(tokens.ItemBreak)
+	} else if current == "continue" {
+		l.emit(tokens.ItemContinue)
+	} else if current == "len" {
+		l.emit(tokens.ItemLen)
+	} else if current == "append" {
+		l.emit(tokens.ItemAppend)
+	} else if current == "type" {
+		l.emit(tokens.ItemKeyType)
+	} else if current == "struct" {
+		l.emit(tokens.ItemStruct)
+	} else if current == "map" {
+		l.emit(tokens.ItemMap)
+	} else if current == "delete" {
+		l.emit(tokens.ItemDelete)
+	} else if current == "has" {
+		l.emit(tokens.ItemHas)
+	} else if current == "in" {
+		l.emit(tokens.ItemIn)
+	} else if current == "const" {
+		l.emit(tokens.ItemConst)
+	} else if current == "void" {
+		l.emit(tokens.ItemVoid)
+	} else if current == "switch" {
+		l.emit(tokens.ItemSwitch)
+	} else if current == "case" {
+		l.emit(tokens.ItemCase)
+	} else if current == "default" {
+		l.emit(tokens.ItemDefault)
 	} else {
 		l.emit(tokens.ItemIdentifier)
 	}
@@ -276,14 +491,52 @@ func lexIdentifier(l *lexer) stateFn {
 	return lexInsideExpression
 }
 
+// Lex a string literal, the opening quote is already consumed.
+// The emitted lexeme is the decoded text, without quotes and with escape sequences resolved.
 func lexQuote(l *lexer) stateFn {
+	var text strings.Builder
 	for {
 		r := l.next()
 		if r == eof || r == '\n' {
 			return l.errorf("Unterminated string literal")
 		} else if r == '"' {
-			l.emit(tokens.ItemText)
+			l.emitLexeme(tokens.ItemText, text.String())
 			return lexInsideExpression
+		} else if r == '\\' {
+			decoded, ok := l.escapeSequence()
+			if !ok {
+				return l.errorf("invalid escape sequence in string literal: %q", l.input[l.start:l.pos])
+			}
+			text.WriteRune(decoded)
+		} else {
+			text.WriteRune(r)
+		}
+	}
+}
+
+// Decode the escape sequence following a backslash.
+func (l *lexer) escapeSequence() (rune, bool) {
+	switch l.next() {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case '"':
+		return '"', true
+	case '\\':
+		return '\\', true
+	case 'u':
+		start := l.pos
+		for range 4 {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", l.next()) {
+				return 0, false
+			}
+		}
+		code, err := strconv.ParseUint(l.input[start:l.pos], 16, 32)
+		if err != nil {
+			return 0, false
 		}
+		return rune(code), true
 	}
+	return 0, false
 }
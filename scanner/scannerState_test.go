@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"dsl/tokens"
+	"slices"
+	"testing"
+)
+
+func scan(input string) ([]tokens.Token, []Comment) {
+	_, items, comments := Lex("test", input)
+	var words []tokens.Token
+	for word := range items {
+		words = append(words, word)
+	}
+	return words, <-comments
+}
+
+func TestComments(t *testing.T) {
+	words, comments := scan("int a = 1; // one\n/* two\nlines */\nint b = a / 2;\n\n// three")
+
+	expected := []Comment{
+		{Text: "// one", Pos: 11, Line: 1},
+		{Text: "/* two\nlines */", Pos: 18, Line: 2},
+		{Text: "// three", Pos: 50, Line: 6},
+	}
+	if !slices.Equal(comments, expected) {
+		t.Fatalf("expected comments %v, got %v", expected, comments)
+	}
+	for _, word := range words {
+		if word.Category == tokens.ItemError {
+			t.Fatalf("unexpected error: %s", word.Lexeme)
+		}
+	}
+}
+
+func TestCommentInsideExpression(t *testing.T) {
+	words, comments := scan("a = 4 /* half */ / 2; // done")
+
+	var lexemes []string
+	for _, word := range words {
+		lexemes = append(lexemes, word.Lexeme)
+	}
+	if expected := []string{"a", "=", "4", "/", "2", ";", ""}; !slices.Equal(lexemes, expected) {
+		t.Fatalf("expected tokens %q, got %q", expected, lexemes)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %v", comments)
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	words, _ := scan("int a = 1; /* open")
+	if last := words[len(words)-1]; last.Category != tokens.ItemError || last.Lexeme != "Unterminated block comment" {
+		t.Fatalf("expected an unterminated block comment error, got %v", last)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestBlockShadowing(t *testing.T) {
+	expectOutput(t, `
+int a = 1;
+string s = "outer";
+{
+  echo(a);
+  int a = 2;
+  a += 10;
+  {
+    echo(a);
+    float a = 3.5;
+    echo(a);
+    s = "changed";
+  }
+  int b = a * 2;
+  echo(b);
+}
+echo(a);
+echo(s);`, 1, 12, 3.5, 24, 1, "changed")
+}
+
+func TestBlockInFunction(t *testing.T) {
+	expectOutput(t, `
+func f(int a) int {
+  {
+    int a = a + 100;
+    echo(a);
+  }
+  return a;
+}
+echo(f(5));
+func counter() func () int {
+  int n = 0;
+  {
+    int n = 100;
+    return () int {
+      n += 1;
+      return n;
+    };
+  }
+}
+c := counter();
+echo(c());
+echo(c());`, 105, 5, 101, 102)
+}
+
+func TestBlockInLoop(t *testing.T) {
+	expectOutput(t, `
+int i = 0;
+while i < 10 {
+  {
+    int j = i * 2;
+    if j > 6 {
+      break;
+    }
+    i++;
+    {
+      int k = 1;
+      continue;
+    }
+  }
+}
+echo(i);`, 4)
+}
+
+func TestBlockScopeEnds(t *testing.T) {
+	expectError(t, `{ int inner = 1; } echo(inner);`, "could not resolve variable name: inner")
+	expectError(t, `{ int a = 1; int a = 2; }`, "Redeclaration of variable: a")
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"dsl/runtime"
+	"dsl/variables"
+	"fmt"
+)
+
+// The state of the current scope when a constant expression began.
+type constant_expression struct {
+	Name         string
+	Instructions int
+	Offset       int
+}
+
+// Begin a constant expression. Constant expressions may be nested, e.g. a default value in a function literal.
+func (s *Storage) BeginConstant(name string) {
+	s.Constants.Push(constant_expression{
+		Name:         name,
+		Instructions: s.InstructionCount(),
+		Offset:       s.CurrentScope.Offset,
+	})
+}
+
+// Evaluate the expression compiled since BeginConstant, and declare the constant with its value.
+func (s *Storage) EndConstant(value variables.Symbol) error {
+	begin := s.Constants.Peek()
+	result, err := s.EvaluateConstant(value)
+	if err != nil {
+		return err
+	}
+
+	if s.ConstantsAhead[begin.Name] && s.CurrentScope.Parent == nil {
+		delete(s.ConstantsAhead, begin.Name)
+	} else if _, exists := s.CurrentScope.Variables[begin.Name]; exists {
+		return fmt.Errorf("redeclaration of variable: %s", begin.Name)
+	}
+	s.CurrentScope.Variables[begin.Name] = variables.SymbolTableEntry{
+		Type:     value.Type,
+		Constant: result,
+	}
+	return nil
+}
+
+// Evaluate the expression compiled since the innermost BeginConstant, which it ends.
+// The instructions and temporaries of the expression are removed, since they are not needed at runtime.
+func (s *Storage) EvaluateConstant(value variables.Symbol) (any, error) {
+	begin := s.Constants.Pop()
+	switch value.Type.BaseType {
+	case variables.INT, variables.FLOAT, variables.BOOL, variables.STRING:
+	default:
+		return nil, fmt.Errorf("invalid constant %s: constants must be int, float, bool or string, got %s", begin.Name, value.Type)
+	}
+
+	instructions := s.CutInstructions(begin.Instructions)
+	frame_size := s.CurrentScope.Offset
+	s.CurrentScope.Offset = begin.Offset
+
+	result, err := runtime.Evaluate(instructions, frame_size, begin.Offset, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constant %s: %w", begin.Name, err)
+	}
+	return result, nil
+}
+
+// Declare a constant of the outermost scope before its declaration is reached, so that it can be used ahead of it.
+func (s *Storage) DeclareConstantAhead(name string, entry variables.SymbolTableEntry) {
+	s.CurrentScope.Variables[name] = entry
+	s.ConstantsAhead[name] = true
+}
+
+// The constants declared in the outermost scope.
+func (s *Storage) GlobalConstants() map[string]variables.SymbolTableEntry {
+	constants := make(map[string]variables.SymbolTableEntry)
+	for name, entry := range s.Scopes[0].Variables {
+		if entry.Constant != nil {
+			constants[name] = entry
+		}
+	}
+	return constants
+}
+
+// Find the constant visible under name. Returns false if name is not a constant.
+func (s *Storage) GetConstant(name string) (any, variables.TypeDefinition, bool) {
+	for scope := s.CurrentScope; scope != nil; scope = scope.Parent {
+		if entry, ok := scope.Variables[name]; ok {
+			return entry.Constant, entry.Type, entry.Constant != nil
+		}
+	}
+	return nil, variables.TypeDefinition{}, false
+}
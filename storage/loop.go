@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"dsl/runtime"
+	"fmt"
+)
+
+type loop_context struct {
+	Scope         *scoped_storage // The scope the loop body is opened in. Jumping out of the body unwinds every scope below it.
+	StartLabel    string
+	ContinueLabel string
+	BreakLabel    string
+	Post          []runtime.InstructionLabelPair // Instructions run after each iteration, before jumping back to StartLabel.
+}
+
+// Start a new loop in the current scope. The label start must point to the first instruction of the loop condition.
+func (s *Storage) BeginLoop(start string) *loop_context {
+	s.Loops.Push(loop_context{
+		Scope:         s.CurrentScope,
+		StartLabel:    start,
+		ContinueLabel: s.NewAutoLabel(),
+		BreakLabel:    s.NewAutoLabel(),
+	})
+	return s.Loops.PeekRef()
+}
+
+// Emit the end of the innermost loop, which must be called after the loop body scope is destroyed:
+// the post statement, the jump back to the condition and the instruction break jumps to.
+func (s *Storage) EndLoop() {
+	loop := s.Loops.Pop()
+
+	s.LoadLabeledInstruction(&runtime.InstrNOP{}, loop.ContinueLabel)
+	s.CurrentScope.Instructions = append(s.CurrentScope.Instructions, loop.Post...)
+	s.LoadInstruction(&runtime.InstrJmp{Label: loop.StartLabel})
+	s.LoadLabeledInstruction(&runtime.InstrNOP{}, loop.BreakLabel)
+}
+
+// Number of instructions in the current scope.
+func (s *Storage) InstructionCount() int {
+	return len(s.CurrentScope.Instructions)
+}
+
+// Remove and return the instructions of the current scope from index start and onwards.
+func (s *Storage) CutInstructions(start int) []runtime.InstructionLabelPair {
+	instructions := make([]runtime.InstructionLabelPair, len(s.CurrentScope.Instructions)-start)
+	copy(instructions, s.CurrentScope.Instructions[start:])
+	s.CurrentScope.Instructions = s.CurrentScope.Instructions[:start]
+	return instructions
+}
+
+func (s *Storage) Break() error {
+	if len(s.Loops) == 0 {
+		return fmt.Errorf("break outside of loop")
+	}
+	return s.exitLoopBody(s.Loops.Peek(), s.Loops.Peek().BreakLabel)
+}
+
+func (s *Storage) Continue() error {
+	if len(s.Loops) == 0 {
+		return fmt.Errorf("continue outside of loop")
+	}
+	return s.exitLoopBody(s.Loops.Peek(), s.Loops.Peek().ContinueLabel)
+}
+
+// Jump to label, ending every scope opened inside the loop so the address stack stays balanced.
+func (s *Storage) exitLoopBody(loop loop_context, label string) error {
+	depth := 0
+	for scope := s.CurrentScope; scope != loop.Scope; scope = scope.Parent {
+		if scope == nil || scope.Function {
+			return fmt.Errorf("cannot jump out of a function to an enclosing loop")
+		}
+		depth += 1
+	}
+
+	for range depth {
+		s.LoadInstruction(&runtime.InstrEndScope{})
+	}
+	s.LoadInstruction(&runtime.InstrJmp{Label: label})
+	return nil
+}
@@ -2,71 +2,254 @@ package storage
 
 import (
 	"dsl/runtime"
+	"dsl/structure"
 	"dsl/variables"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 )
 
 type Storage struct {
-	CurrentScope *scoped_storage
-	Scopes       []scoped_storage
-	LabelIndex   int //Used for auto-generated labels. They must be unique across scopes.
-	NextLabel    string
+	CurrentScope   *scoped_storage
+	Scopes         []scoped_storage
+	LabelIndex     int //Used for auto-generated labels. They must be unique across scopes.
+	NextLabel      string
+	Loops          structure.Stack[loop_context] //Loops currently being compiled, innermost on top.
+	Types          map[string]variables.TypeDefinition
+	TypesAhead     map[string]bool                      //Types declared ahead of their definition, see DeclareTypeAhead.
+	ConstantsAhead map[string]bool                      //Constants declared ahead of their definition, see DeclareConstantAhead.
+	Upcoming       map[string]bool                      //Variables of the outermost scope whose declaration is not reached yet, see DeclareUpcoming.
+	Headers        bool                                 //Set when only the declarations of a program are parsed, with function bodies left out.
+	Constants      structure.Stack[constant_expression] //Constant expressions being compiled, innermost on top.
+	Switches       structure.Stack[switch_context]      //Switch statements currently being compiled, innermost on top.
 }
 
 type scoped_storage struct {
 	Parent       *scoped_storage
 	Variables    map[string]variables.SymbolTableEntry
 	Offset       int
-	Instructions []runtime.InstructionLabelPair //Instructions and associated label from statements/expressions in the local scope.
+	Instructions []runtime.InstructionLabelPair        //Instructions and associated label from statements/expressions in the local scope.
+	Function     bool                                  //Set if this is the outermost scope of a function body.
+	Name         string                                //The name of the function, for function scopes. Empty for function literals.
+	Definition   variables.TypeDefinition              //The type of the function, for function scopes.
+	Begin        *runtime.InstrBeginScope              //The instruction entering the scope, for block scopes.
+	LoadFunction *runtime.InstrLoadFunction            //The instruction creating the function, for function scopes.
+	Captures     []variables.Symbol                    //Variables from enclosing scopes used by the function, resolved in the enclosing scope.
+	CaptureIndex map[string]int                        //Index in Captures by variable name.
+	Overloads    map[string][]string                   //Names of the functions declared under a name in this scope, see declareOverload.
+	Declared     map[string]*runtime.InstrLoadFunction //Functions declared ahead of their definition, see DeclareFunction.
 }
 
 func newScopedStorage() scoped_storage {
 	return scoped_storage{
-		Variables: make(map[string]variables.SymbolTableEntry),
+		Variables:    make(map[string]variables.SymbolTableEntry),
+		CaptureIndex: make(map[string]int),
+		Overloads:    make(map[string][]string),
+		Declared:     make(map[string]*runtime.InstrLoadFunction),
 	}
 }
 
 func NewStorage() Storage {
-	storage := Storage{}
+	storage := Storage{
+		Types:          make(map[string]variables.TypeDefinition),
+		TypesAhead:     make(map[string]bool),
+		ConstantsAhead: make(map[string]bool),
+		Upcoming:       make(map[string]bool),
+	}
 	storage.Scopes = append(storage.Scopes, newScopedStorage())
 	storage.CurrentScope = &storage.Scopes[0]
 	return storage
 }
 
 func (s *Storage) NewFunction(name string, definition variables.TypeDefinition) {
-	func_symbol, err := s.NewVariable(definition, name)
+	load_function := s.takeDeclaration(name, definition)
+	if load_function == nil {
+		var err error
+		_, load_function, err = s.newFunctionVariable(name, definition)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	s.newFunctionScope(definition, load_function)
+	s.CurrentScope.Name = name
+	s.NewLabel(load_function.Label)
+}
+
+// Declare a function ahead of its definition, so that it can be called before it is defined.
+// The function is created where it is declared, and NewFunction compiles its body when it is defined.
+func (s *Storage) DeclareFunction(name string, definition variables.TypeDefinition) error {
+	overload_name, load_function, err := s.newFunctionVariable(name, definition)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	s.CurrentScope.Declared[overload_name] = load_function
+	return nil
+}
 
-	label := s.NewAutoLabel()
-	s.LoadInstruction(&runtime.InstrLoadFunction{
+// Create the variable holding a function, and the instruction creating the function when the scope is run.
+// Returns the name the variable is stored under, see declareOverload.
+func (s *Storage) newFunctionVariable(name string, definition variables.TypeDefinition) (string, *runtime.InstrLoadFunction, error) {
+	overload_name, err := s.declareOverload(name, definition)
+	if err != nil {
+		return "", nil, err
+	}
+	func_symbol, err := s.NewVariable(definition, overload_name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	load_function := &runtime.InstrLoadFunction{
 		Symbol: *func_symbol,
-		Label:  label,
+		Label:  s.NewAutoLabel(),
+	}
+	s.LoadInstruction(load_function)
+	return overload_name, load_function, nil
+}
+
+// Find the declaration of a function that is being defined, or nil if it was not declared ahead.
+func (s *Storage) takeDeclaration(name string, definition variables.TypeDefinition) *runtime.InstrLoadFunction {
+	for _, overload_name := range s.CurrentScope.Overloads[name] {
+		load_function, ok := s.CurrentScope.Declared[overload_name]
+		if ok && load_function.Symbol.Type.Equals(definition) {
+			delete(s.CurrentScope.Declared, overload_name)
+			return load_function
+		}
+	}
+	return nil
+}
+
+// A function declared in the outermost scope.
+type FunctionDeclaration struct {
+	Name       string
+	Definition variables.TypeDefinition
+}
+
+// Set the variables of the outermost scope to their zero value when the program starts.
+// Functions declared ahead may be called before the declaration of a variable they read is reached.
+func (s *Storage) InitializeGlobals() {
+	var names []string
+	for name, entry := range s.CurrentScope.Variables {
+		if entry.Constant == nil && entry.Type.ZeroValue() != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return s.CurrentScope.Variables[names[i]].Offset < s.CurrentScope.Variables[names[j]].Offset
 	})
 
-	s.newFunctionScope(definition)
-	s.NewLabel(label)
+	var instructions []runtime.InstructionLabelPair
+	for _, name := range names {
+		entry := s.CurrentScope.Variables[name]
+		instructions = append(instructions, runtime.InstructionLabelPair{
+			Instruction: &runtime.InstrLoadImmediate{
+				Dest:  variables.Symbol{Offset: entry.Offset, Type: entry.Type},
+				Value: entry.Type.ZeroValue(),
+			},
+		})
+	}
+	s.CurrentScope.Instructions = append(instructions, s.CurrentScope.Instructions...)
+}
+
+// Note the variables declared in the outermost scope after the current position, see unresolved.
+// They are not declared ahead, unlike functions, so they cannot be used until their declaration is reached.
+func (s *Storage) DeclareUpcoming(names []string) {
+	for _, name := range names {
+		s.Upcoming[name] = true
+	}
+}
+
+// The functions declared in the outermost scope, in order of declaration.
+func (s *Storage) Functions() []FunctionDeclaration {
+	global := s.Scopes[0]
+
+	var overload_names []string
+	names := make(map[string]string)
+	for name, overloads := range global.Overloads {
+		for _, overload_name := range overloads {
+			overload_names = append(overload_names, overload_name)
+			names[overload_name] = name
+		}
+	}
+	sort.Slice(overload_names, func(i, j int) bool {
+		return global.Variables[overload_names[i]].Offset < global.Variables[overload_names[j]].Offset
+	})
+
+	var functions []FunctionDeclaration
+	for _, overload_name := range overload_names {
+		functions = append(functions, FunctionDeclaration{
+			Name:       names[overload_name],
+			Definition: global.Variables[overload_name].Type,
+		})
+	}
+	return functions
+}
+
+// Functions declared with the same name in a scope are overloads, and must take different argument types.
+// The first is stored under name, the others under a name that cannot be written in the source.
+func (s *Storage) declareOverload(name string, definition variables.TypeDefinition) (string, error) {
+	overloads, ok := s.CurrentScope.Overloads[name]
+	if !ok {
+		s.CurrentScope.Overloads[name] = []string{name}
+		return name, nil
+	}
+	for _, overload := range overloads {
+		if s.CurrentScope.Variables[overload].Type.ArgumentList.SameTypes(definition.ArgumentList) {
+			return "", fmt.Errorf("redeclaration of function %s with the same argument types", name)
+		}
+	}
+
+	overload_name := fmt.Sprintf("%s#%d", name, len(overloads))
+	s.CurrentScope.Overloads[name] = append(overloads, overload_name)
+	return overload_name, nil
+}
+
+// Resolve every function declared under name in the innermost scope declaring it.
+// For a variable, or a function that is not overloaded, this is the single symbol GetVarAddr resolves.
+func (s *Storage) GetOverloads(name string) ([]variables.Symbol, error) {
+	for scope := s.CurrentScope; scope != nil; scope = scope.Parent {
+		if _, ok := scope.Variables[name]; !ok {
+			continue
+		}
+		names, ok := scope.Overloads[name]
+		if !ok {
+			names = []string{name}
+		}
+
+		var symbols []variables.Symbol
+		for _, overload_name := range names {
+			symbol, err := s.GetVarAddr(overload_name)
+			if err != nil {
+				return nil, err
+			}
+			symbols = append(symbols, symbol)
+		}
+		return symbols, nil
+	}
+	return nil, s.unresolved(name)
 }
 
 func (s *Storage) NewImplicitFunction(definition variables.TypeDefinition) variables.Symbol {
 	func_symbol := s.NewLiteral(definition)
 	label := s.NewAutoLabel()
 
-	s.LoadInstruction(&runtime.InstrLoadFunction{
+	load_function := &runtime.InstrLoadFunction{
 		Symbol: func_symbol,
 		Label:  label,
-	})
+	}
+	s.LoadInstruction(load_function)
 
-	s.newFunctionScope(definition)
+	s.newFunctionScope(definition, load_function)
 	s.NewLabel(label)
 	return func_symbol 
 }
 
-func (s *Storage) newFunctionScope(definition variables.TypeDefinition) {
-	s.NewScope()
+func (s *Storage) newFunctionScope(definition variables.TypeDefinition, load_function *runtime.InstrLoadFunction) {
+	scope := s.NewScope()
+	scope.Function = true
+	scope.Definition = definition
+	scope.LoadFunction = load_function
 
 	// Create variable entries for the arguments. They are placed first in the function's symbol table
 	for _, arg := range definition.ArgumentList {
@@ -86,14 +269,34 @@ func (s *Storage) NewScope() *scoped_storage {
 
 func (s *Storage) DestroyFunctionScope(runTime *runtime.Runtime) (int, int) {
 	start, end := runTime.LoadInstructions(s.CurrentScope.Instructions)
+	if s.CurrentScope.LoadFunction != nil {
+		s.CurrentScope.LoadFunction.Captures = s.CurrentScope.Captures
+		s.CurrentScope.LoadFunction.FrameSize = s.CurrentScope.Offset
+	}
 
 	s.CurrentScope = s.CurrentScope.Parent
 
 	return start, end
 }
 
+// Open a block scope. Its variables are reserved when the returned instruction is executed.
+func (s *Storage) BeginScope() *runtime.InstructionLabelPair {
+	begin := &runtime.InstrBeginScope{}
+	instr := s.LoadInstruction(begin)
+	s.NewScope().Begin = begin
+	return instr
+}
+
+// The number of variables in the current scope. For a function scope, this is the size of its frame.
+func (s *Storage) FrameSize() int {
+	return s.CurrentScope.Offset
+}
+
 func (s *Storage) DestroyScope() {
 	instructions := s.CurrentScope.Instructions
+	if s.CurrentScope.Begin != nil {
+		s.CurrentScope.Begin.Size = s.CurrentScope.Offset
+	}
 
 	s.CurrentScope = s.CurrentScope.Parent
 	s.CurrentScope.Instructions = append(s.CurrentScope.Instructions, instructions...)
@@ -107,6 +310,13 @@ func (s *Storage) NewLiteral(vartype variables.TypeDefinition) variables.Symbol
 	return sym
 }
 
+// Allocate the consecutive variables holding the values of a tuple, see variables.Symbol.Values.
+func (s *Storage) NewTuple(vartype variables.TypeDefinition) variables.Symbol {
+	sym := variables.Symbol{Scope: 0, Offset: s.CurrentScope.Offset, Type: vartype}
+	s.CurrentScope.Offset += len(vartype.Elements)
+	return sym
+}
+
 func (s *Storage) NewVariable(vartype variables.TypeDefinition, name string) (*variables.Symbol, error) {
 	_, exists := s.CurrentScope.Variables[name]
 	if exists {
@@ -114,6 +324,10 @@ func (s *Storage) NewVariable(vartype variables.TypeDefinition, name string) (*v
 		return nil, fmt.Errorf("redeclaration of variable: %s\n", name)
 	}
 
+	if s.CurrentScope.Parent == nil {
+		delete(s.Upcoming, name)
+	}
+
 	addr := s.CurrentScope.Offset
 	s.CurrentScope.Variables[name] = variables.SymbolTableEntry{
 		Type:   vartype,
@@ -126,29 +340,74 @@ func (s *Storage) NewVariable(vartype variables.TypeDefinition, name string) (*v
 	return &variables.Symbol{Scope: 0, Offset: s.CurrentScope.Offset - 1, Type: vartype}, nil
 }
 
+// Resolve name for reading. Constants are inlined, loading their value into a new literal.
 func (s *Storage) GetVarAddr(name string) (variables.Symbol, error) {
-	scope := s.CurrentScope
-	symbol, ok := variables.SymbolTableEntry{}, false
+	if value, _type, ok := s.GetConstant(name); ok {
+		addr := s.NewLiteral(_type)
+		s.LoadInstruction(&runtime.InstrLoadImmediate{
+			Dest:  addr,
+			Value: value,
+		})
+		return addr, nil
+	}
+	return s.resolve(name, s.CurrentScope)
+}
+
+// Resolve name as the destination of an assignment, which must not be a constant.
+func (s *Storage) GetAssignableAddr(name string) (variables.Symbol, error) {
+	if _, _, ok := s.GetConstant(name); ok {
+		return variables.Symbol{}, fmt.Errorf("cannot assign to %s, it is a constant", name)
+	}
+	return s.resolve(name, s.CurrentScope)
+}
+
+// Resolve name as seen from scope. A variable declared outside of the function containing scope
+// is captured by the function, and resolves to its slot in the function's closure.
+func (s *Storage) resolve(name string, scope *scoped_storage) (variables.Symbol, error) {
 	scopeOffset := 0
 	for {
-		symbol, ok = (*scope).Variables[name]
+		symbol, ok := (*scope).Variables[name]
 		if ok {
-			break
+			return variables.Symbol{
+				Scope:  scopeOffset,
+				Offset: symbol.Offset,
+				Type:   symbol.Type,
+			}, nil
+		}
+		if scope.Function {
+			return s.capture(name, scope)
 		}
 		scope = (*scope).Parent
 		if scope == nil {
-			break
+			return variables.Symbol{}, s.unresolved(name)
 		}
 		scopeOffset += 1
 	}
+}
+
+// The error for a name that does not resolve to any variable.
+func (s *Storage) unresolved(name string) error {
+	if s.Upcoming[name] {
+		return fmt.Errorf("variable %s is used before its declaration", name)
+	}
+	return fmt.Errorf("could not resolve variable name: %s", name)
+}
 
+func (s *Storage) capture(name string, function *scoped_storage) (variables.Symbol, error) {
+	index, ok := function.CaptureIndex[name]
 	if !ok {
-		return variables.Symbol{}, fmt.Errorf("could not resolve variable name: %s", name)
+		outer, err := s.resolve(name, function.Parent)
+		if err != nil {
+			return variables.Symbol{}, err
+		}
+		index = len(function.Captures)
+		function.Captures = append(function.Captures, outer)
+		function.CaptureIndex[name] = index
 	}
 	return variables.Symbol{
-		Scope:  scopeOffset,
-		Offset: symbol.Offset,
-		Type:   symbol.Type,
+		Offset:   index,
+		Type:     function.Captures[index].Type,
+		Captured: true,
 	}, nil
 }
 
@@ -166,6 +425,63 @@ func (s *Storage) LoadInstruction(instruction runtime.Instruction) *runtime.Inst
 	return &s.CurrentScope.Instructions[len(s.CurrentScope.Instructions)-1]
 }
 
+// The last instruction loaded into the current scope, or nil if there is none.
+func (s *Storage) LastInstruction() *runtime.InstructionLabelPair {
+	if len(s.CurrentScope.Instructions) == 0 {
+		return nil
+	}
+	return &s.CurrentScope.Instructions[len(s.CurrentScope.Instructions)-1]
+}
+
+// The instructions loaded into the current scope after its last enclosed block. They are run in the frame
+// of the current scope, while the instructions of a block use variables of the block's own frame.
+func (s *Storage) RecentInstructions() []runtime.InstructionLabelPair {
+	instructions := s.CurrentScope.Instructions
+	for i := len(instructions) - 1; i >= 0; i-- {
+		if _, ok := instructions[i].Instruction.(*runtime.InstrEndScope); ok {
+			return instructions[i+1:]
+		}
+	}
+	return instructions
+}
+
+// Whether the current scope is inside a function body, rather than at the top level.
+func (s *Storage) InFunction() bool {
+	return s.functionScope() != nil
+}
+
+// The return type of the function containing the current scope.
+func (s *Storage) ReturnType() (variables.TypeDefinition, error) {
+	function := s.functionScope()
+	if function == nil {
+		return variables.TypeDefinition{}, fmt.Errorf("return outside of function")
+	}
+	return *function.Definition.ReturnType, nil
+}
+
+// Whether the end of the current scope can be reached, see runtime.ReachesEnd.
+func (s *Storage) ReachesEnd() bool {
+	return runtime.ReachesEnd(s.CurrentScope.Instructions)
+}
+
+// The name of the function containing the current scope, see scoped_storage.Name.
+func (s *Storage) FunctionName() string {
+	if function := s.functionScope(); function != nil {
+		return function.Name
+	}
+	return ""
+}
+
+// The outermost scope of the function containing the current scope, or nil at the top level.
+func (s *Storage) functionScope() *scoped_storage {
+	for scope := s.CurrentScope; scope != nil; scope = scope.Parent {
+		if scope.Function {
+			return scope
+		}
+	}
+	return nil
+}
+
 func (s *Storage) InsertInstructionAt(instruction runtime.Instruction, label string, offset int) {
 
 }
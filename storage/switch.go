@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"dsl/runtime"
+	"dsl/variables"
+)
+
+// A switch statement being compiled. The cases compare the subject in order, and each case jumps
+// to its body when it matches, or on to the next case when it does not.
+type switch_context struct {
+	Subject      variables.Symbol
+	EndLabel     string       // The end of the switch, where every case body ends.
+	NextLabel    string       // The comparisons of the next case, empty before the first case.
+	DefaultLabel string       // The body of the default case, empty if there is none.
+	Values       map[any]bool // The case values so far, to report duplicates.
+}
+
+func (s *Storage) BeginSwitch(subject variables.Symbol) {
+	s.Switches.Push(switch_context{
+		Subject:  subject,
+		EndLabel: s.NewAutoLabel(),
+		Values:   make(map[any]bool),
+	})
+}
+
+// The innermost switch statement being compiled.
+func (s *Storage) CurrentSwitch() *switch_context {
+	return s.Switches.PeekRef()
+}
+
+// Start the comparisons of a case, where the previous case continues when it does not match.
+func (s *Storage) BeginCase() {
+	sw := s.Switches.PeekRef()
+	if sw.NextLabel != "" {
+		s.LoadLabeledInstruction(&runtime.InstrNOP{}, sw.NextLabel)
+	}
+	sw.NextLabel = s.NewAutoLabel()
+}
+
+// Emit the end of the innermost switch: when no case matches, the default case is run if there is one.
+func (s *Storage) EndSwitch() {
+	sw := s.Switches.Pop()
+	if sw.NextLabel != "" {
+		s.LoadLabeledInstruction(&runtime.InstrNOP{}, sw.NextLabel)
+	}
+	if sw.DefaultLabel != "" {
+		s.LoadInstruction(&runtime.InstrJmp{Label: sw.DefaultLabel})
+	}
+	s.LoadLabeledInstruction(&runtime.InstrNOP{}, sw.EndLabel)
+}
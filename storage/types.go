@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"dsl/variables"
+	"fmt"
+)
+
+// Declare a new named type. The type is visible from here on, but cannot be used before DefineType is called.
+// Type names are global, regardless of the scope they are declared in.
+func (s *Storage) DeclareType(name string) error {
+	if s.TypesAhead[name] {
+		delete(s.TypesAhead, name)
+		return nil
+	}
+	if _, exists := s.Types[name]; exists {
+		return fmt.Errorf("redeclaration of type: %s", name)
+	}
+	s.Types[name] = variables.TypeDefinition{BaseType: variables.INVALID, Name: name}
+	return nil
+}
+
+// Declare a type before its declaration is reached, so that it can be used ahead of it.
+func (s *Storage) DeclareTypeAhead(name string, definition variables.TypeDefinition) {
+	s.Types[name] = definition
+	s.TypesAhead[name] = true
+}
+
+func (s *Storage) DefineType(name string, definition variables.TypeDefinition) {
+	s.Types[name] = definition
+}
+
+func (s *Storage) IsTypeName(name string) bool {
+	_, exists := s.Types[name]
+	return exists
+}
+
+func (s *Storage) GetType(name string) (variables.TypeDefinition, error) {
+	definition, exists := s.Types[name]
+	if !exists {
+		return definition, fmt.Errorf("could not resolve type name: %s", name)
+	}
+	if definition.BaseType == variables.INVALID {
+		return definition, fmt.Errorf("invalid recursive type: %s", name)
+	}
+	return definition, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestStrings(t *testing.T) {
+	expectOutput(t, `
+string name = "elev";
+string full = name + "ator";
+echo(full);
+echo(name == "elev");
+echo(name != "elev");
+func greet(string who) string {
+  return "hi " + who;
+}
+echo(greet("bob"));`, "elevator", true, false, "hi bob")
+}
+
+func TestStringEscapes(t *testing.T) {
+	expectOutput(t, `echo("tab\t\"quoted\"\\ æ");`, "tab\t\"quoted\"\\ æ")
+	expectError(t, `echo("\q");`, "invalid escape sequence in string literal")
+}
+
+func TestStringErrors(t *testing.T) {
+	expectError(t, `string s = "a" - "b";`, "invalid arithmetic operator for type string")
+	expectError(t, `string s = "a" + 1;`, "invalid arithmetic on string and int")
+	expectError(t, `string s = 1;`, "invalid type assignment: expected string, got int")
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestStructs(t *testing.T) {
+	expectOutput(t, `
+type Elevator struct {
+  int floor;
+  bool door_open;
+  []bool orders;
+}
+type Building struct {
+  [2]Elevator elevators;
+  string name;
+}
+Elevator e = Elevator{floor: 2, orders: []bool{false, true}};
+echo(e);
+echo(e.floor);
+e.floor = 3;
+Elevator f = e;
+f.door_open = true;
+f.orders[0] = true;
+echo(e);
+echo(f);
+Building b = Building{name: "A"};
+b.elevators[1] = e;
+b.elevators[0].floor = 7;
+echo(b);
+echo(b.elevators[1].orders[1]);
+echo(Elevator{}.floor);`,
+		"{2 false [false true]}", 2, "{3 false [false true]}", "{3 true [true true]}",
+		"{[{7 false []} {3 false [false true]}] A}", true, 0)
+}
+
+func TestStructArguments(t *testing.T) {
+	expectOutput(t, `
+type Elevator struct {
+  int floor;
+}
+func move(Elevator el, int to) Elevator {
+  el.floor = to;
+  return el;
+}
+Elevator e = Elevator{floor: 3};
+Elevator g = move(e, 9);
+echo(g.floor);
+echo(e.floor);`, 9, 3)
+}
+
+func TestStructErrors(t *testing.T) {
+	const point = "type Point struct { int x; int y; }\n"
+	expectError(t, point+`Point p = Point{x: 1, x: 2};`, "duplicate field x in literal of type Point")
+	expectError(t, point+`Point p = Point{x: true};`, "invalid value for field x: expected int, got bool")
+	expectError(t, point+`Point p = Point{}; echo(p.z);`, "type Point has no field z")
+	expectError(t, point+`Point p = Point{}; p.x = "a";`, "invalid type assignment: expected int, got string")
+	expectError(t, `int i = 0; echo(i.x);`, "cannot access field x of int, a non-struct value")
+	expectError(t, `type P struct { int x; bool x; }`, "duplicate field x in type P")
+	expectError(t, point+point, "redeclaration of type: Point")
+	expectError(t, `type Node struct { Node next; }`, "invalid recursive type: Node")
+}
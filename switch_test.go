@@ -0,0 +1,105 @@
+package main
+
+import "testing"
+
+func TestSwitch(t *testing.T) {
+	expectOutput(t, `
+const IDLE = 0;
+const MOVING = 1;
+const DOOR_OPEN = 2;
+func describe(int state) string {
+  switch state {
+  case IDLE:
+    return "idle";
+  case MOVING, MOVING + 10:
+    return "moving";
+  default:
+    return "unknown";
+  case DOOR_OPEN:
+    return "door open";
+  }
+  return "unreachable";
+}
+echo(describe(0));
+echo(describe(11));
+echo(describe(2));
+echo(describe(7));`, "idle", "moving", "door open", "unknown")
+}
+
+func TestSwitchOnBoolsAndStrings(t *testing.T) {
+	expectOutput(t, `
+switch "b" + "c" {
+case "a":
+  echo("a");
+case "bc":
+  echo("bc");
+  switch true {
+  case false:
+    echo("no");
+  case true:
+    echo("nested");
+  }
+}
+switch false {
+case true:
+  echo("true");
+}
+echo("done");`, "bc", "nested", "done")
+}
+
+func TestBreakInSwitch(t *testing.T) {
+	expectOutput(t, `
+for int i = 0; i < 5; i++ {
+  switch i % 3 {
+  case 0:
+    echo("fizz");
+  case 1:
+  case 2:
+    if i > 3 {
+      break;
+    }
+    echo("two");
+  }
+}
+int x = 1;
+switch x {
+default:
+  int x = 5;
+  echo(x);
+}
+echo(x);`, "fizz", "two", "fizz", 5, 1)
+}
+
+func TestSwitchErrors(t *testing.T) {
+	expectError(t, `
+switch 1 {
+case 1:
+  echo(1);
+case 2, 1:
+  echo(2);
+}`, "duplicate case 1 in switch")
+	expectError(t, `
+switch 1 {
+case "a":
+  echo(1);
+}`, "invalid case value: expected int, got string")
+	expectError(t, `
+switch 1 {
+default:
+  echo(1);
+default:
+  echo(2);
+}`, "multiple defaults in switch")
+	expectError(t, `
+int y = 1;
+switch 1 {
+case y:
+  echo(1);
+}`, "invalid constant case value: expression is not constant")
+	expectError(t, `
+switch 1 {
+case 1 / 0:
+  echo(1);
+}`, "invalid constant case value: invalid constant expression")
+	expectError(t, `switch 1.5 { default: echo(1); }`, "cannot switch on a value of type float")
+}
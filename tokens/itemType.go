@@ -52,6 +52,41 @@ const (
 	ItemIf
 	ItemElse
 	ItemReturn
+	ItemWhile
+	ItemFor
+	ItemBreak
+	ItemContinue
+	ItemKeyString
+	ItemKeyFloat
+	ItemFloat
+	ItemBracketOpen
+	ItemBracketClose
+	ItemLen
+	ItemAppend
+	ItemKeyType
+	ItemStruct
+	ItemTypeName // An identifier that names a declared type. Never emitted by the scanner, see LRParser.Parse
+	ItemDot
+	ItemColon
+	ItemMap
+	ItemDelete
+	ItemHas
+	ItemIn
+	ItemPlusEquals
+	ItemMinusEquals
+	ItemMultEquals
+	ItemDivEquals
+	ItemModEquals
+	ItemIncrement
+	ItemDecrement
+	ItemDeclare
+	ItemConst
+	ItemVoid
+	ItemEllipsis
+	ItemSwitch
+	ItemCase
+	ItemDefault
+	ItemQuestion
 	TERMINALS_LENGTH
 )
 
@@ -89,6 +124,44 @@ const (
 	NTBeginElseIf
 	NTTypeList
 	NTImplicitFunctionDefinition
+	NTWhileBegin
+	NTWhileHeader
+	NTLoopScopeClose
+	NTForBegin
+	NTForInit
+	NTForCondition
+	NTForPost
+	NTForHeader
+	NTArrayType
+	NTStructHeader
+	NTFieldDeclaration
+	NTFieldDeclarationList
+	NTFieldInit
+	NTFieldInitList
+	NTMapType
+	NTMapInit
+	NTMapInitList
+	NTForInHeader
+	NTUnary
+	NTOrLeft
+	NTAndLeft
+	NTUpdate
+	NTAssignOp
+	NTConstHeader
+	NTDefaultHeader
+	NTSwitchHeader
+	NTCaseList
+	NTCase
+	NTCaseHeader
+	NTCaseBegin
+	NTCaseValues
+	NTCaseSeparator
+	NTOrExpr
+	NTConditionalHeader
+	NTConditionalThen
+	NTReturnType
+	NTReturnValues
+	NTIdentList
 	NONTERMINALS_LENGTH
 )
 
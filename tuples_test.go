@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestMultipleReturnValues(t *testing.T) {
+	expectOutput(t, `
+const UP = 1;
+func status(int floor) (int, bool) {
+  return floor * 2, floor > 1;
+}
+f, moving := status(2);
+echo(f);
+echo(moving);
+f, moving = status(0);
+echo(f);
+echo(moving);
+func forward(int floor) (int, bool) {
+  return status(floor + 1);
+}
+a, b := forward(3);
+echo(a);
+echo(b);
+func three() (int, string, bool) {
+  return UP, "up", true;
+}
+x, s, t := three();
+echo(x);
+echo(s);
+echo(t);
+func single() (int) {
+  return 5;
+}
+echo(single() + 1);`, 4, true, 0, false, 8, true, 1, "up", true, 6)
+}
+
+func TestRecursiveMultipleReturnValues(t *testing.T) {
+	expectOutput(t, `
+func divmod(int a, int b) (int, int) {
+  if (a < b) {
+    return 0, a;
+  }
+  q, r := divmod(a - b, b);
+  return q + 1, r;
+}
+q, r := divmod(17, 5);
+echo(q);
+echo(r);`, 3, 2)
+}
+
+func TestFunctionLiteralReturningTuple(t *testing.T) {
+	expectOutput(t, `
+g := (int v) (int, int) { return v, v * v; };
+p, sq := g(4);
+echo(p);
+echo(sq);
+func apply(func (int) (int, int) fn) int {
+  u, w := fn(3);
+  return u + w;
+}
+echo(apply(g));`, 4, 16, 12)
+}
+
+func TestTupleErrors(t *testing.T) {
+	status := `
+func status() (int, bool) {
+  return 1, true;
+}
+`
+	expectError(t, status+`echo(status() + 1);`, "function call returning (int, bool) used as a single value")
+	expectError(t, status+`a, b, c := status();`, "assignment mismatch: 3 variables but 2 values")
+	expectError(t, `
+func one() int { return 1; }
+a, b := one();`, "assignment mismatch: 2 variables but 1 value")
+	expectError(t, status+`a := status();`, "cannot declare a from a function call returning (int, bool)")
+	expectError(t, `
+func f() (int, bool) {
+  return 1;
+}`, "invalid return type: expected (int, bool), got int")
+	expectError(t, `
+func f() int {
+  return 1, 2;
+}`, "invalid return type: expected int, got (int, int)")
+}
@@ -0,0 +1,74 @@
+package variables
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Holds the elements of an array. Arrays live on the heap, and a variable of array type holds a reference to one.
+// Arrays have value semantics in the language, so the reference must be copied with CopyValue when assigned.
+type ArrayVar struct {
+	Elements []any
+}
+
+func (a *ArrayVar) String() string {
+	var elements []string
+	for _, element := range a.Elements {
+		elements = append(elements, fmt.Sprint(element))
+	}
+	return "[" + strings.Join(elements, " ") + "]"
+}
+
+// Copy a value, such that the copy does not share any heap-allocated data with the original.
+func CopyValue(value any) any {
+	switch v := value.(type) {
+	case *ArrayVar:
+		elements := make([]any, len(v.Elements))
+		for i := range v.Elements {
+			elements[i] = CopyValue(v.Elements[i])
+		}
+		return &ArrayVar{Elements: elements}
+	case *StructVar:
+		fields := make([]any, len(v.Fields))
+		for i := range v.Fields {
+			fields[i] = CopyValue(v.Fields[i])
+		}
+		return &StructVar{Fields: fields}
+	case *MapVar:
+		entries := make(map[any]any, len(v.Entries))
+		for key := range v.Entries {
+			entries[key] = CopyValue(v.Entries[key])
+		}
+		return &MapVar{Entries: entries}
+	}
+	return value
+}
+
+// The value a variable of this type holds when it is not explicitly initialized.
+func (def TypeDefinition) ZeroValue() any {
+	switch def.BaseType {
+	case INT:
+		return 0
+	case FLOAT:
+		return 0.0
+	case BOOL:
+		return false
+	case STRING:
+		return ""
+	case ARRAY:
+		elements := make([]any, def.Length)
+		for i := range elements {
+			elements[i] = def.ElementType.ZeroValue()
+		}
+		return &ArrayVar{Elements: elements}
+	case STRUCT:
+		fields := make([]any, len(def.Fields))
+		for i := range def.Fields {
+			fields[i] = def.Fields[i].Definition.ZeroValue()
+		}
+		return &StructVar{Fields: fields}
+	case MAP:
+		return &MapVar{Entries: make(map[any]any)}
+	}
+	return nil
+}
@@ -1,17 +1,22 @@
 package variables
 
 import (
-	"dsl/structure"
 	"fmt"
+	"slices"
 	"strings"
 )
 
 type Argument struct {
 	Definition TypeDefinition
 	Identifier string
+	Variadic   bool // Only the last argument can be variadic. Its definition is then an array of the argument type.
+	Default    any  // Value used when the argument is left out of a call. Nil if the argument is required.
 }
 
 func (arg Argument) String() string {
+	if arg.Variadic {
+		return fmt.Sprintf("%s... %s", arg.Definition.ElementType.String(), arg.Identifier)
+	}
 	return fmt.Sprintf("%s %s", arg.Definition.String(), arg.Identifier)
 }
 
@@ -22,9 +27,41 @@ type TypeDefinition struct {
 	//Used if type is a function pointer.
 	ArgumentList ArgumentList
 	ReturnType   *TypeDefinition
+
+	//Used if type is an array or a map. Length is 0 for dynamic arrays.
+	ElementType *TypeDefinition
+	Length      int
+	KeyType     *TypeDefinition
+
+	//Used if type is a struct. Struct types are nominal, two struct types are equal if their names are.
+	Name   string
+	Fields []Argument
+
+	//Used if type is a tuple, the types of its values.
+	Elements []TypeDefinition
 }
 
 func (arg TypeDefinition) String() string {
+	if arg.BaseType == STRUCT {
+		return arg.Name
+	}
+	if arg.BaseType == MAP {
+		return fmt.Sprintf("map[%s]%s", arg.KeyType.String(), arg.ElementType.String())
+	}
+	if arg.BaseType == TUPLE {
+		var elements []string
+		for _, element := range arg.Elements {
+			elements = append(elements, element.String())
+		}
+		return "(" + strings.Join(elements, ", ") + ")"
+	}
+	if arg.BaseType == ARRAY {
+		if arg.Length > 0 {
+			return fmt.Sprintf("[%d]%s", arg.Length, arg.ElementType.String())
+		}
+		return "[]" + arg.ElementType.String()
+	}
+
 	s := ""
 	s += arg.BaseType.String()
 
@@ -49,41 +86,89 @@ func (a TypeDefinition) Equals(b TypeDefinition) bool {
 	}
 
 	if a.BaseType == FUNC {
-		if len(a.ArgumentList) != len(b.ArgumentList) {
-			return false
-		}
-		if !a.ReturnType.Equals(*b.ReturnType) {
+		if !a.ArgumentList.SameTypes(b.ArgumentList) || !a.ReturnType.Equals(*b.ReturnType) {
 			return false
 		}
+	}
 
-		for i := range a.ArgumentList {
-			if !a.ArgumentList[i].Definition.Equals(b.ArgumentList[i].Definition) {
-				return false
-			}
-		}
+	if a.BaseType == ARRAY {
+		return a.Length == b.Length && a.ElementType.Equals(*b.ElementType)
+	}
+
+	if a.BaseType == STRUCT {
+		return a.Name == b.Name
+	}
+
+	if a.BaseType == MAP {
+		return a.KeyType.Equals(*b.KeyType) && a.ElementType.Equals(*b.ElementType)
+	}
+
+	if a.BaseType == TUPLE {
+		return slices.EqualFunc(a.Elements, b.Elements, TypeDefinition.Equals)
 	}
 
 	return true
 }
 
-// Holds the label of the function it is referring to.
+// Holds the label of the function it is referring to, and the variables it has captured.
 type FunctionVar struct {
-	Label        string
-	AddressStack structure.Stack[int]
+	Label     string
+	Env       []*Cell // Captured variables, indexed by the Offset of symbols with Captured set.
+	FrameSize int     // Number of variables reserved for a call: arguments, locals and temporaries.
+}
+
+// A variable moved to the heap, because it is captured by a function.
+// Cells are shared between the scope that declared the variable and every function that captured it,
+// so they outlive the scope.
+type Cell struct {
+	Value any
 }
 
 // Verify an argument list of symbols.
 // If the argument list length and type of each argument does not match, return false.
+// A variadic argument list accepts any number of trailing arguments, including none.
 func (list ArgumentList) ValidateArgumentList(symbols []Symbol) bool {
-	if len(symbols) != len(list) {
+	fixed := list
+	if list.IsVariadic() {
+		fixed = list[:len(list)-1]
+		if len(symbols) < len(fixed) {
+			return false
+		}
+	} else if len(symbols) != len(list) {
 		return false
 	}
 
-	for i := range list {
-		if !symbols[i].Type.Equals(list[i].Definition) {
+	for i := range fixed {
+		if !fixed[i].Definition.accepts(symbols[i]) {
+			return false
+		}
+	}
+	for _, symbol := range symbols[len(fixed):] {
+		if !list[len(list)-1].Definition.ElementType.accepts(symbol) {
 			return false
 		}
 	}
 
 	return true
 }
+
+// Whether two argument lists take arguments of the same types, ignoring names and default values.
+func (list ArgumentList) SameTypes(other ArgumentList) bool {
+	if len(list) != len(other) {
+		return false
+	}
+	for i := range list {
+		if !list[i].Definition.Equals(other[i].Definition) || list[i].Variadic != other[i].Variadic {
+			return false
+		}
+	}
+	return true
+}
+
+func (list ArgumentList) IsVariadic() bool {
+	return len(list) > 0 && list[len(list)-1].Variadic
+}
+
+func (def TypeDefinition) accepts(symbol Symbol) bool {
+	return def.BaseType == ANY || symbol.Type.Equals(def)
+}
@@ -0,0 +1,55 @@
+package variables
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Holds the entries of a map. Keys are int, bool or string values.
+// Like arrays, maps live on the heap and have value semantics, see CopyValue.
+type MapVar struct {
+	Entries map[any]any
+}
+
+// The keys of the map in sorted order, so iteration and printing is deterministic.
+func (m *MapVar) SortedKeys() []any {
+	keys := make([]any, 0, len(m.Entries))
+	for key := range m.Entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, compareKeys)
+	return keys
+}
+
+func (m *MapVar) String() string {
+	var entries []string
+	for _, key := range m.SortedKeys() {
+		entries = append(entries, fmt.Sprintf("%v:%v", key, m.Entries[key]))
+	}
+	return "map[" + strings.Join(entries, " ") + "]"
+}
+
+// Order two map keys of the same type. false is ordered before true.
+func compareKeys(a any, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return cmp.Compare(a, b.(string))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if a {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// Check if values of the type can be used as map keys.
+func (def TypeDefinition) IsValidKey() bool {
+	return def.BaseType == INT || def.BaseType == BOOL || def.BaseType == STRING
+}
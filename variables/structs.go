@@ -0,0 +1,30 @@
+package variables
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Holds the field values of a struct, in the order the fields are declared.
+// Like arrays, structs live on the heap and have value semantics, see CopyValue.
+type StructVar struct {
+	Fields []any
+}
+
+func (s *StructVar) String() string {
+	var fields []string
+	for _, field := range s.Fields {
+		fields = append(fields, fmt.Sprint(field))
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
+
+// Find the index and type of the field with the given name.
+func (def TypeDefinition) Field(name string) (int, TypeDefinition, error) {
+	for i, field := range def.Fields {
+		if field.Identifier == name {
+			return i, field.Definition, nil
+		}
+	}
+	return -1, TypeDefinition{}, fmt.Errorf("type %s has no field %s", def, name)
+}
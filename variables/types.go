@@ -13,6 +13,13 @@ const (
 	BOOL
 	FUNC
 	NONE
+	STRING
+	FLOAT
+	ARRAY
+	STRUCT
+	MAP
+	ANY   // Only used by built-in functions, accepts an argument of any type.
+	TUPLE // The values returned by a function returning multiple values, see Symbol.Values.
 )
 
 func TypeFromString(s string) (Type, error) {
@@ -21,6 +28,10 @@ func TypeFromString(s string) (Type, error) {
 		return INT, nil
 	case "bool":
 		return BOOL, nil
+	case "string":
+		return STRING, nil
+	case "float":
+		return FLOAT, nil
 	case "void":
 		return NONE, nil
 	}
@@ -38,6 +49,20 @@ func (t Type) String() string {
 		return "void"
 	case FUNC:
 		return "func"
+	case STRING:
+		return "string"
+	case FLOAT:
+		return "float"
+	case ARRAY:
+		return "array"
+	case STRUCT:
+		return "struct"
+	case MAP:
+		return "map"
+	case ANY:
+		return "any"
+	case TUPLE:
+		return "tuple"
 	case INVALID:
 		return ""
 	}
@@ -46,12 +71,32 @@ func (t Type) String() string {
 }
 
 type Symbol struct {
-	Scope  int
-	Offset int
-	Type   TypeDefinition
+	Scope    int
+	Offset   int
+	Type     TypeDefinition
+	Captured bool // The symbol refers to a variable captured by the current function, Offset indexes into its environment.
+}
+
+// Whether both symbols refer to the same variable.
+func (s Symbol) SameVariable(other Symbol) bool {
+	return s.Scope == other.Scope && s.Offset == other.Offset && s.Captured == other.Captured
+}
+
+// The values held by the symbol. The values of a tuple are held in consecutive variables,
+// starting with the variable of the tuple symbol. Any other symbol holds a single value.
+func (s Symbol) Values() []Symbol {
+	if s.Type.BaseType != TUPLE {
+		return []Symbol{s}
+	}
+	values := make([]Symbol, len(s.Type.Elements))
+	for i := range values {
+		values[i] = Symbol{Scope: s.Scope, Offset: s.Offset + i, Type: s.Type.Elements[i]}
+	}
+	return values
 }
 
 type SymbolTableEntry struct {
-	Offset int
-	Type   TypeDefinition
+	Offset   int
+	Type     TypeDefinition
+	Constant any // The value of a constant, which has no offset. Nil for variables.
 }